@@ -0,0 +1,225 @@
+package client
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Configures the FetchById cache of an account client. Fresh entries are served without a
+// request, stale entries are revalidated with If-None-Match or If-Modified-Since.
+type AccountCacheSetting struct {
+	// Time an entry is served without asking the API.
+	TTL time.Duration
+	// Maximum number of accounts kept, evicting the least recently used one.
+	MaxEntries int
+}
+
+var ACCOUNT_CACHE_SETTING_DEFAULT = &AccountCacheSetting{
+	TTL:        30 * time.Second,
+	MaxEntries: 1000,
+}
+
+// Counts how FetchById calls were answered by the cache.
+type AccountCacheStats struct {
+	// Calls served from a fresh entry without a request.
+	Hits int64
+	// Calls which fetched the account because no usable entry was cached.
+	Misses int64
+	// Calls served from a stale entry after the API answered 304 Not Modified.
+	Revalidations int64
+	// Entries dropped to stay within MaxEntries.
+	Evictions int64
+}
+
+type accountCacheEntry struct {
+	id           string
+	account      *AccountData
+	links        *Links
+	header       http.Header
+	etag         string
+	lastModified string
+	expires      time.Time
+}
+
+// LRU cache of fetched accounts keyed by account ID.
+type accountCache struct {
+	mutex   sync.Mutex
+	setting AccountCacheSetting
+	entries map[string]*list.Element
+	order   *list.List
+	stats   AccountCacheStats
+	// Fetches in flight by account ID, so an invalidation during a fetch keeps its result out.
+	fetches map[string]*accountCacheFetch
+}
+
+// Number of fetches of an account in flight, and how often the account was invalidated meanwhile.
+type accountCacheFetch struct {
+	count      int
+	generation uint64
+}
+
+// Creates an account cache, filling unset values from the default setting.
+func newAccountCache(setting *AccountCacheSetting) *accountCache {
+	merged := *ACCOUNT_CACHE_SETTING_DEFAULT
+	if setting != nil {
+		if setting.TTL > 0 {
+			merged.TTL = setting.TTL
+		}
+		if setting.MaxEntries > 0 {
+			merged.MaxEntries = setting.MaxEntries
+		}
+	}
+	return &accountCache{
+		setting: merged,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+		fetches: map[string]*accountCacheFetch{},
+	}
+}
+
+// Returns a copy of the cached entry of the account and whether it is still fresh.
+func (cache *accountCache) get(id string, now time.Time) (*accountCacheEntry, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	element, ok := cache.entries[id]
+	if !ok {
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	entry := *element.Value.(*accountCacheEntry)
+	return &entry, now.Before(entry.expires)
+}
+
+// Registers a fetch of the account which is about to be sent. Callers must end the fetch.
+// Returns the invalidation generation the result of the fetch is stored under.
+func (cache *accountCache) begin(id string) uint64 {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	fetch, ok := cache.fetches[id]
+	if !ok {
+		fetch = &accountCacheFetch{}
+		cache.fetches[id] = fetch
+	}
+	fetch.count++
+	return fetch.generation
+}
+
+// Unregisters a fetch of the account started with begin.
+func (cache *accountCache) end(id string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if fetch, ok := cache.fetches[id]; ok {
+		fetch.count--
+		if fetch.count == 0 {
+			delete(cache.fetches, id)
+		}
+	}
+}
+
+// Stores a fetched account along with the validators of the response, unless the account was
+// invalidated since the fetch began at the generation.
+func (cache *accountCache) put(id string, generation uint64, account *AccountData, links *Links, httpResponse *http.Response, now time.Time) {
+	entry := &accountCacheEntry{
+		id:           id,
+		account:      account.clone(),
+		links:        links.clone(),
+		header:       httpResponse.Header.Clone(),
+		etag:         httpResponse.Header.Get("ETag"),
+		lastModified: httpResponse.Header.Get("Last-Modified"),
+		expires:      now.Add(cache.setting.TTL),
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if fetch, ok := cache.fetches[id]; ok && fetch.generation != generation {
+		return
+	}
+	if element, ok := cache.entries[id]; ok {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[id] = cache.order.PushFront(entry)
+	for cache.order.Len() > cache.setting.MaxEntries {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*accountCacheEntry).id)
+		cache.stats.Evictions++
+	}
+}
+
+// Marks a revalidated entry fresh again.
+func (cache *accountCache) refresh(id string, now time.Time) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if element, ok := cache.entries[id]; ok {
+		element.Value.(*accountCacheEntry).expires = now.Add(cache.setting.TTL)
+	}
+}
+
+// Drops the cached entry of the account, and keeps the results of fetches in flight out of the cache.
+func (cache *accountCache) remove(id string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if fetch, ok := cache.fetches[id]; ok {
+		fetch.generation++
+	}
+	if element, ok := cache.entries[id]; ok {
+		cache.order.Remove(element)
+		delete(cache.entries, id)
+	}
+}
+
+// Counts a call served from a fresh entry.
+func (cache *accountCache) hit() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.stats.Hits++
+}
+
+// Counts a call which fetched the account.
+func (cache *accountCache) miss() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.stats.Misses++
+}
+
+// Counts a call served from a stale entry confirmed by the API.
+func (cache *accountCache) revalidated() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.stats.Revalidations++
+}
+
+// Returns a snapshot of the statistics.
+func (cache *accountCache) snapshot() AccountCacheStats {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.stats
+}
+
+// Sets the conditional request headers for revalidating the entry.
+func (entry *accountCacheEntry) setConditions(httpRequest *http.Request) bool {
+	if entry.etag != "" {
+		httpRequest.Header.Set("If-None-Match", entry.etag)
+	}
+	if entry.lastModified != "" {
+		httpRequest.Header.Set("If-Modified-Since", entry.lastModified)
+	}
+	return entry.etag != "" || entry.lastModified != ""
+}
+
+// Builds the response handed to callers for a fresh cache hit, which sent no request.
+func (entry *accountCacheEntry) response() *http.Response {
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     entry.header.Clone(),
+		Body:       http.NoBody,
+	}
+}
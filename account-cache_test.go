@@ -0,0 +1,188 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const TEST_ETAG = `"v1"`
+
+func etagHandler(hits, conditionalHits *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		switch r.Method {
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+			return
+		}
+		w.Header().Set("ETag", TEST_ETAG)
+		if r.Header.Get("If-None-Match") == TEST_ETAG {
+			atomic.AddInt32(conditionalHits, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	}
+}
+
+func TestAccountCache_HitWithinTTL(t *testing.T) {
+	var hits, conditionalHits int32
+	uncachedClient, closeServer := prepareTestAccountClientWithSetting(nil, etagHandler(&hits, &conditionalHits))
+	defer closeServer()
+	accountClient := NewCachedAccountClient(uncachedClient.HttpClient, &AccountCacheSetting{TTL: time.Minute})
+
+	first, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if err != nil {
+		t.Fatalf("FAILED: FetchById returned error: %v", err)
+	}
+	first.Attributes.Name[0] = "changed by caller"
+	second, _, res, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if err != nil || res.StatusCode != http.StatusOK || res.Header.Get("ETag") != TEST_ETAG {
+		t.Fatalf("FAILED: expected a cached 200 response, got %v %v", res, err)
+	}
+	if hits != 1 {
+		t.Errorf("FAILED: expected a single request, got %d", hits)
+	}
+	if second.Attributes.Name[0] == "changed by caller" {
+		t.Errorf("FAILED: expected callers to get their own copy of the cached account")
+	}
+	if stats := accountClient.CacheStats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("FAILED: expected 1 hit and 1 miss, got %+v", stats)
+	}
+	t.Log("SUCCESS: account was served from the cache")
+}
+
+func TestAccountCache_RevalidatesWithETag(t *testing.T) {
+	var hits, conditionalHits int32
+	uncachedClient, closeServer := prepareTestAccountClientWithSetting(nil, etagHandler(&hits, &conditionalHits))
+	defer closeServer()
+	accountClient := NewCachedAccountClient(uncachedClient.HttpClient, &AccountCacheSetting{TTL: time.Millisecond})
+
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	time.Sleep(5 * time.Millisecond)
+	account, _, res, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if err != nil || account == nil || account.ID != SINGLE_ACCOUNT_ID {
+		t.Fatalf("FAILED: expected the cached account after revalidation, got %v %v", account, err)
+	}
+	if res.StatusCode != http.StatusNotModified || conditionalHits != 1 {
+		t.Errorf("FAILED: expected a conditional request answered with 304, got %d and %d conditional hits",
+			res.StatusCode, conditionalHits)
+	}
+	if stats := accountClient.CacheStats(); stats.Revalidations != 1 || stats.Misses != 1 {
+		t.Errorf("FAILED: expected 1 revalidation and 1 miss, got %+v", stats)
+	}
+}
+
+func TestAccountCache_DeleteInvalidates(t *testing.T) {
+	var hits, conditionalHits int32
+	uncachedClient, closeServer := prepareTestAccountClientWithSetting(nil, etagHandler(&hits, &conditionalHits))
+	defer closeServer()
+	accountClient := NewCachedAccountClient(uncachedClient.HttpClient, &AccountCacheSetting{TTL: time.Minute})
+
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if _, err := accountClient.DeleteAccount(SINGLE_ACCOUNT_ID, 0); err != nil {
+		t.Fatalf("FAILED: DeleteAccount returned error: %v", err)
+	}
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if hits != 3 || conditionalHits != 0 {
+		t.Errorf("FAILED: expected an unconditional fetch after delete, got %d requests and %d conditional",
+			hits, conditionalHits)
+	}
+}
+
+func TestAccountCache_DeleteDuringFetchInvalidates(t *testing.T) {
+	var hits, conditionalHits int32
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := etagHandler(&hits, &conditionalHits)
+	uncachedClient, closeServer := prepareTestAccountClientWithSetting(nil,
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && atomic.LoadInt32(&hits) == 0 {
+				close(entered)
+				<-release
+			}
+			handler(w, r)
+		})
+	defer closeServer()
+	accountClient := NewCachedAccountClient(uncachedClient.HttpClient, &AccountCacheSetting{TTL: time.Minute})
+
+	fetched := make(chan struct{})
+	go func() {
+		accountClient.FetchById(SINGLE_ACCOUNT_ID)
+		close(fetched)
+	}()
+	<-entered
+	if _, err := accountClient.DeleteAccount(SINGLE_ACCOUNT_ID, 0); err != nil {
+		t.Fatalf("FAILED: DeleteAccount returned error: %v", err)
+	}
+	close(release)
+	<-fetched
+
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if stats := accountClient.CacheStats(); stats.Hits != 0 || hits != 3 || conditionalHits != 0 {
+		t.Errorf("FAILED: expected the fetch after delete to miss the cache, got %+v and %d requests", stats, hits)
+	} else {
+		t.Log("SUCCESS: a fetch in flight during a delete was not cached")
+	}
+}
+
+func TestAccountCache_CreateInvalidates(t *testing.T) {
+	var hits, conditionalHits int32
+	uncachedClient, closeServer := prepareTestAccountClientWithSetting(nil, etagHandler(&hits, &conditionalHits))
+	defer closeServer()
+	accountClient := NewCachedAccountClient(uncachedClient.HttpClient, &AccountCacheSetting{TTL: time.Minute})
+
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	accountClient.CreateAccount(populateSingleAccountDataUnitTest())
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if stats := accountClient.CacheStats(); stats.Hits != 0 || stats.Misses != 2 {
+		t.Errorf("FAILED: expected create to invalidate the cached account, got %+v", stats)
+	}
+}
+
+func TestAccountCache_LRUEviction(t *testing.T) {
+	cache := newAccountCache(&AccountCacheSetting{TTL: time.Minute, MaxEntries: 2})
+	httpResponse := &http.Response{Header: http.Header{}}
+	now := time.Now()
+	cache.put("a", 0, &AccountData{ID: "a"}, nil, httpResponse, now)
+	cache.put("b", 0, &AccountData{ID: "b"}, nil, httpResponse, now)
+	cache.get("a", now)
+	cache.put("c", 0, &AccountData{ID: "c"}, nil, httpResponse, now)
+
+	if entry, _ := cache.get("b", now); entry != nil {
+		t.Errorf("FAILED: expected the least recently used entry to be evicted")
+	}
+	if entry, fresh := cache.get("a", now); entry == nil || !fresh {
+		t.Errorf("FAILED: expected the recently used entry to be kept")
+	}
+	if stats := cache.snapshot(); stats.Evictions != 1 {
+		t.Errorf("FAILED: expected 1 eviction, got %+v", stats)
+	}
+}
+
+func TestAccountCache_NotFoundIsNotCached(t *testing.T) {
+	var hits int32
+	uncachedClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error_message": "record does not exist"}`)
+	})
+	defer closeServer()
+	accountClient := NewCachedAccountClient(uncachedClient.HttpClient, nil)
+
+	for i := 0; i < 2; i++ {
+		if _, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID); err == nil {
+			t.Errorf("FAILED: expected not found error")
+		}
+	}
+	if hits != 2 {
+		t.Errorf("FAILED: expected errors not to be cached, got %d requests", hits)
+	}
+}
@@ -1,17 +1,35 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Error returned when an account ID can not be used in a request URL, or is not a UUID although
+// the account client requires one.
+var ErrInvalidAccountID = errors.New("invalid account id")
+
+var accountIdPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
 type AccountParams struct {
 	Number string
 	Size   int
 }
 
-type AccountClient RestClient
+type AccountClient struct {
+	HttpClient *HttpClient
+	// Rejects account IDs which are not UUIDs with ErrInvalidAccountID before a request is sent.
+	RequireUUID bool
+	cache       *accountCache
+	flights     *flightGroup
+}
 
 // Creates a new account client using http client.
 func NewAccountClient(httpClient *HttpClient) *AccountClient {
@@ -20,35 +38,113 @@ func NewAccountClient(httpClient *HttpClient) *AccountClient {
 	}
 	return &AccountClient{
 		HttpClient: httpClient,
+		flights:    newFlightGroup(),
 	}
 }
 
+// Creates a new account client using http client, caching FetchById results with the cache setting.
+func NewCachedAccountClient(httpClient *HttpClient, setting *AccountCacheSetting) *AccountClient {
+	accountClient := NewAccountClient(httpClient)
+	accountClient.cache = newAccountCache(setting)
+	return accountClient
+}
+
 // Gets a single account using the account ID.
 // Returns account data, links and http response.
 func (accountClient *AccountClient) FetchById(id string) (*AccountData, *Links, *http.Response, error) {
+	return accountClient.FetchByIdWithContext(context.Background(), id)
+}
+
+// Gets a single account using context and the account ID. Concurrent calls for the same ID share
+// one request, and a caller whose context is done stops waiting without cancelling it for the others.
+// Returns account data, links and http response. With a cache, fresh accounts are returned along
+// with a response built from the cached headers, and revalidated accounts along with the 304 response.
+func (accountClient *AccountClient) FetchByIdWithContext(ctx context.Context, id string) (*AccountData, *Links, *http.Response, error) {
+	if accountClient.flights == nil {
+		return accountClient.fetchById(ctx, id)
+	}
+	result := accountClient.flights.do(ctx, id, func(ctx context.Context) fetchResult {
+		account, links, httpResponse, err := accountClient.fetchById(ctx, id)
+		return newFetchResult(account, links, httpResponse, err)
+	})
+	return result.account.clone(), result.links.clone(), result.response(), result.err
+}
+
+// Gets a single account using context and the account ID, consulting the cache when configured.
+// Returns account data, links and http response.
+func (accountClient *AccountClient) fetchById(ctx context.Context, id string) (*AccountData, *Links, *http.Response, error) {
+	if err := accountClient.checkAccountId(id); err != nil {
+		return nil, nil, nil, err
+	}
+	var cached *accountCacheEntry
+	if accountClient.cache != nil {
+		var fresh bool
+		cached, fresh = accountClient.cache.get(id, time.Now())
+		if fresh {
+			accountClient.cache.hit()
+			return cached.account.clone(), cached.links.clone(), cached.response(), nil
+		}
+	}
+	var generation uint64
+	if accountClient.cache != nil {
+		generation = accountClient.cache.begin(id)
+		defer accountClient.cache.end(id)
+	}
+
 	accountResponse := new(AccountData)
 	links := new(Links)
-	url := fetchAccountApiUrl(accountClient.HttpClient.BaseURL, id)
+	fetchURL, err := fetchAccountApiUrl(accountClient.HttpClient.BaseURL, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	httpRequest, err := accountClient.HttpClient.newHttpRequest("GET", fetchURL, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	conditional := cached != nil && cached.setConditions(httpRequest)
 
-	httpResponse, err := accountClient.HttpClient.Get(url, nil, accountResponse, links)
+	httpResponse, err := accountClient.HttpClient.perform(ctx, httpRequest, accountResponse, links)
 	if err != nil {
-		log.Printf("Error occurred while fetching account by id: %v\n", err)
+		if accountClient.cache != nil && httpResponse != nil && httpResponse.StatusCode == http.StatusNotFound {
+			accountClient.cache.remove(id)
+		}
+		accountClient.HttpClient.logger.Log(LOG_LEVEL_ERROR, "Error occurred while fetching account by id",
+			Field("id", id), Field("error", err))
 		return nil, nil, httpResponse, err
 	}
 
+	if accountClient.cache != nil {
+		if conditional && httpResponse.StatusCode == http.StatusNotModified {
+			accountClient.cache.revalidated()
+			accountClient.cache.refresh(id, time.Now())
+			return cached.account.clone(), cached.links.clone(), httpResponse, nil
+		}
+		accountClient.cache.miss()
+		accountClient.cache.put(id, generation, accountResponse, links, httpResponse, time.Now())
+	}
 	return accountResponse, links, httpResponse, nil
 }
 
 // List accounts with optional page parameters.
 // Returns list of accounts' data, links and http response.
 func (accountClient *AccountClient) ListAccount(params *AccountParams) ([]*AccountData, *Links, *http.Response, error) {
+	return accountClient.ListAccountWithContext(context.Background(), params)
+}
+
+// List accounts using context with optional page parameters.
+// Returns list of accounts' data, links and http response.
+func (accountClient *AccountClient) ListAccountWithContext(ctx context.Context, params *AccountParams) ([]*AccountData, *Links, *http.Response, error) {
 	accounts := new([]*AccountData)
 	links := new(Links)
-	url := listAccountApiUrl(accountClient.HttpClient.BaseURL, params)
+	listURL, err := listAccountApiUrl(accountClient.HttpClient.BaseURL, params)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
-	httpResponse, err := accountClient.HttpClient.Get(url, nil, accounts, links)
+	httpResponse, err := accountClient.HttpClient.GetWithContext(ctx, listURL, nil, accounts, links)
 	if err != nil {
-		log.Printf("Error occurred while fetching account list: %v\n", err)
+		accountClient.HttpClient.logger.Log(LOG_LEVEL_ERROR, "Error occurred while fetching account list",
+			Field("error", err))
 		return nil, nil, httpResponse, err
 	}
 
@@ -58,13 +154,21 @@ func (accountClient *AccountClient) ListAccount(params *AccountParams) ([]*Accou
 // Creates a bank account with provided account data payload.
 // Returns account data, links and http response.
 func (accountClient *AccountClient) CreateAccount(payload *AccountData) (*AccountData, *Links, *http.Response, error) {
+	return accountClient.CreateAccountWithContext(context.Background(), payload)
+}
+
+// Creates a bank account using context with provided account data payload.
+// Returns account data, links and http response.
+func (accountClient *AccountClient) CreateAccountWithContext(ctx context.Context, payload *AccountData) (*AccountData, *Links, *http.Response, error) {
 	accountResponse := new(AccountData)
 	links := new(Links)
-	httpResponse, err := accountClient.HttpClient.Post(accountClient.HttpClient.BaseURL, payload, accountResponse, links)
+	httpResponse, err := accountClient.HttpClient.PostWithContext(ctx, accountClient.HttpClient.BaseURL, payload, accountResponse, links)
 	if err != nil {
-		log.Printf("Error occurred while creating account: %v\n", err)
+		accountClient.HttpClient.logger.Log(LOG_LEVEL_ERROR, "Error occurred while creating account",
+			Field("error", err))
 		return nil, nil, httpResponse, err
 	}
+	accountClient.InvalidateCache(accountResponse.ID)
 
 	return accountResponse, links, httpResponse, nil
 }
@@ -72,34 +176,104 @@ func (accountClient *AccountClient) CreateAccount(payload *AccountData) (*Accoun
 // Deletes a account using the account ID and version number.
 // Returns http response.
 func (accountClient *AccountClient) DeleteAccount(id string, version int) (*http.Response, error) {
-	url := deleteAccountApiUrl(accountClient.HttpClient.BaseURL, id, version)
+	return accountClient.DeleteAccountWithContext(context.Background(), id, version)
+}
 
-	httpResponse, err := accountClient.HttpClient.Delete(url)
+// Deletes a account using context, the account ID and version number.
+// Returns http response.
+func (accountClient *AccountClient) DeleteAccountWithContext(ctx context.Context, id string, version int) (*http.Response, error) {
+	if err := accountClient.checkAccountId(id); err != nil {
+		return nil, err
+	}
+	deleteURL, err := deleteAccountApiUrl(accountClient.HttpClient.BaseURL, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResponse, err := accountClient.HttpClient.DeleteWithContext(ctx, deleteURL)
+	if httpResponse != nil {
+		accountClient.InvalidateCache(id)
+	}
 	if err != nil {
-		log.Printf("Error occurred while deleting account: %v\n", err)
+		accountClient.HttpClient.logger.Log(LOG_LEVEL_ERROR, "Error occurred while deleting account",
+			Field("id", id), Field("version", version), Field("error", err))
 		return httpResponse, err
 	}
 
 	return httpResponse, nil
 }
 
+// Drops the cached account, e.g. after it was changed by another client.
+func (accountClient *AccountClient) InvalidateCache(id string) {
+	if accountClient.cache != nil {
+		accountClient.cache.remove(id)
+	}
+}
+
+// Returns the hit, miss, revalidation and eviction counts of the cache.
+func (accountClient *AccountClient) CacheStats() AccountCacheStats {
+	if accountClient.cache == nil {
+		return AccountCacheStats{}
+	}
+	return accountClient.cache.snapshot()
+}
+
+// Returns an error when the account client requires UUIDs and the account ID is not one.
+func (accountClient *AccountClient) checkAccountId(id string) error {
+	if accountClient.RequireUUID && !accountIdPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q is not a UUID", ErrInvalidAccountID, id)
+	}
+	return nil
+}
+
 // Populates fetch account API URL from base URL and account ID
-func fetchAccountApiUrl(baseURL string, id string) string {
-	url := fmt.Sprintf("%s/%s", baseURL, id)
-	return url
+func fetchAccountApiUrl(baseURL string, id string) (string, error) {
+	accountURL, err := accountApiUrl(baseURL, id)
+	if err != nil {
+		return "", err
+	}
+	return accountURL.String(), nil
 }
 
 // Populates list account API URL from base URL and page parameters
-func listAccountApiUrl(baseURL string, params *AccountParams) string {
-	url := baseURL
+func listAccountApiUrl(baseURL string, params *AccountParams) (string, error) {
+	listURL, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
 	if params != nil {
-		url = fmt.Sprintf("%s?page[number]=%s&page[size]=%d", baseURL, params.Number, params.Size)
+		query := listURL.Query()
+		query.Set("page[number]", params.Number)
+		query.Set("page[size]", strconv.Itoa(params.Size))
+		listURL.RawQuery = query.Encode()
 	}
-	return url
+	return listURL.String(), nil
 }
 
 // Populates delete account API URL from base URL, account ID and version
-func deleteAccountApiUrl(baseURL string, id string, version int) string {
-	url := fmt.Sprintf("%s/%s?version=%d", baseURL, id, version)
-	return url
+func deleteAccountApiUrl(baseURL string, id string, version int) (string, error) {
+	accountURL, err := accountApiUrl(baseURL, id)
+	if err != nil {
+		return "", err
+	}
+	query := accountURL.Query()
+	query.Set("version", strconv.Itoa(version))
+	accountURL.RawQuery = query.Encode()
+	return accountURL.String(), nil
+}
+
+// Parses the base URL and appends the escaped account ID as a single path segment. Empty and dot
+// IDs are rejected, as they would point the request at the account list or a parent path.
+func accountApiUrl(baseURL string, id string) (*url.URL, error) {
+	if id == "" || id == "." || id == ".." {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAccountID, id)
+	}
+	accountURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	escapedPath := strings.TrimSuffix(accountURL.EscapedPath(), "/")
+	accountURL.Path = strings.TrimSuffix(accountURL.Path, "/") + "/" + id
+	accountURL.RawPath = escapedPath + "/" + url.PathEscape(id)
+	return accountURL, nil
 }
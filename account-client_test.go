@@ -1,6 +1,7 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -26,6 +27,24 @@ func prepareTestAccountClientWithWrongUrl() (*AccountClient, *http.ServeMux, fun
 	return accountClient, multiplexer, server.Close
 }
 
+// Starts a test server serving the handler for the accounts resource and every account below it.
+// Returns an account client created from a copy of the setting pointing at the server.
+func prepareTestAccountClientWithSetting(setting *ClientSetting, handler http.HandlerFunc) (*AccountClient, func()) {
+	multiplexer := http.NewServeMux()
+	server := httptest.NewServer(multiplexer)
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE+"/", handler)
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE, handler)
+	copied := ClientSetting{}
+	if setting != nil {
+		copied = *setting
+	}
+	copied.BaseURL = server.URL + UNIT_ACCOUNTS_API_BASE
+	if copied.Timeout == 0 {
+		copied.Timeout = INTEGRATION_TIME_OUT
+	}
+	return NewAccountClient(NewHttpClient(&copied)), server.Close
+}
+
 func TestAccountClient_NewAccountClient(t *testing.T) {
 	accountClient := NewAccountClient(nil)
 	if accountClient != nil {
@@ -62,54 +81,6 @@ func TestAccountClient_FetchById(t *testing.T) {
 	}
 }
 
-func TestAccountClient_FetchById_NotFound(t *testing.T) {
-	accountClient, multiplexer, close := prepareTestAccountClient()
-	defer close()
-
-	muxUrl := UNIT_ACCOUNTS_API_BASE + "/" + WRONG_ACCOUNT_ID
-	multiplexer.HandleFunc(muxUrl, func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNotFound)
-		_, err := fmt.Fprint(w, WRONG_ACCOUNT_MOCK_RESPONSE)
-		if err != nil {
-			t.Errorf("FAILED: Error occured while writing mock response: %v\n", err)
-		}
-	})
-
-	_, _, res, err := accountClient.FetchById(WRONG_ACCOUNT_ID)
-	if res.StatusCode == 404 {
-		t.Logf("SUCCESS: status code expected %v, got %v\n", 404, res.StatusCode)
-	} else if err != nil {
-		t.Errorf("FAILED: Error while calling FetchById: %v\n", err)
-	} else {
-		t.Errorf("FAILED: status code expected %v, got %v\n", 404, res.StatusCode)
-	}
-}
-
-func TestAccountClient_ListAccount(t *testing.T) {
-	accountClient, multiplexer, close := prepareTestAccountClient()
-	defer close()
-
-	muxUrl := UNIT_ACCOUNTS_API_BASE
-	multiplexer.HandleFunc(muxUrl, func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, err := fmt.Fprint(w, MULTI_ACCOUNT_MOCK_RESPONSE)
-		if err != nil {
-			t.Errorf("FAILED: Error occured while writing mock response: %v\n", err)
-		}
-	})
-
-	list, _, res, err := accountClient.ListAccount(nil)
-	if err != nil {
-		t.Errorf("FAILED: ListAccount returned error: %v", err)
-	}
-
-	if len(list) <= 0 {
-		t.Errorf("FAILED: ListAccount call expected %+v, got %+v", 2, len(list))
-	} else {
-		t.Logf("SUCCESS: status code expected %v, got %v\n", 200, res.StatusCode)
-	}
-}
-
 func TestAccountClient_ListAccountWithWrongUrl(t *testing.T) {
 	accountClient, multiplexer, close := prepareTestAccountClientWithWrongUrl()
 	defer close()
@@ -129,31 +100,6 @@ func TestAccountClient_ListAccountWithWrongUrl(t *testing.T) {
 	}
 }
 
-func TestAccountClient_CreateAccount(t *testing.T) {
-	accountClient, multiplexer, close := prepareTestAccountClient()
-	defer close()
-
-	muxUrl := UNIT_ACCOUNTS_API_BASE
-	multiplexer.HandleFunc(muxUrl, func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusCreated)
-		_, err := fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
-		if err != nil {
-			t.Errorf("FAILED: Error occured while writing mock response: %v\n", err)
-		}
-	})
-
-	accountData := populateSingleAccountDataUnitTest()
-
-	_, _, res, err := accountClient.CreateAccount(accountData)
-	if err != nil {
-		t.Errorf("FAILED: Error while calling CreateAccount: %v\n", err)
-	} else if res.StatusCode == 201 {
-		t.Logf("SUCCESS: status code expected %v, got %v\n", 201, res.StatusCode)
-	} else {
-		t.Errorf("SUCCESS: status code expected %v, got %v\n", 201, res.StatusCode)
-	}
-}
-
 func TestAccountClient_CreateAccountWithWrongUrl(t *testing.T) {
 	accountClient, multiplexer, close := prepareTestAccountClientWithWrongUrl()
 	defer close()
@@ -200,8 +146,8 @@ func TestAccountClient_CreateAccountWithWrongData(t *testing.T) {
 	}
 }
 
-func TestAccountClient_DeleteAccount(t *testing.T) {
-	accountClient, multiplexer, close := prepareTestAccountClient()
+func TestAccountClient_DeleteAccountWithWrongUrl(t *testing.T) {
+	accountClient, multiplexer, close := prepareTestAccountClientWithWrongUrl()
 	defer close()
 
 	muxUrl := UNIT_ACCOUNTS_API_BASE + "/" + SINGLE_ACCOUNT_ID
@@ -209,46 +155,90 @@ func TestAccountClient_DeleteAccount(t *testing.T) {
 		w.WriteHeader(http.StatusNoContent)
 	})
 
-	res, err := accountClient.DeleteAccount(SINGLE_ACCOUNT_ID, 0)
+	_, err := accountClient.DeleteAccount(SINGLE_ACCOUNT_ID, 0)
 	if err != nil {
-		t.Errorf("FAILED: Error while calling DeleteAccount: %v\n", err)
-	} else if res.StatusCode == 204 {
-		t.Logf("SUCCESS: status code expected %v, got %v\n", 204, res.StatusCode)
-	} else {
-		t.Errorf("SUCCESS: status code expected %v, got %v\n", 204, res.StatusCode)
+		t.Logf("SUCCESS: DeleteAccount returned error: %v", err)
 	}
 }
 
-func TestAccountClient_DeleteAccountWithWrongUrl(t *testing.T) {
-	accountClient, multiplexer, close := prepareTestAccountClientWithWrongUrl()
-	defer close()
+func prepareTestAccountClientRecordingUris() (*AccountClient, *[]string, func()) {
+	var requestURIs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURIs = append(requestURIs, r.RequestURI)
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	}))
+	accountClient := NewAccountClient(NewHttpClient(nil))
+	accountClient.HttpClient.BaseURL = server.URL + UNIT_ACCOUNTS_API_BASE
+	return accountClient, &requestURIs, server.Close
+}
 
-	muxUrl := UNIT_ACCOUNTS_API_BASE + "/" + SINGLE_ACCOUNT_ID
-	multiplexer.HandleFunc(muxUrl, func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNoContent)
-	})
+func TestAccountClient_IdInjection(t *testing.T) {
+	accountClient, requestURIs, closeServer := prepareTestAccountClientRecordingUris()
+	defer closeServer()
 
-	_, err := accountClient.DeleteAccount(SINGLE_ACCOUNT_ID, 0)
-	if err != nil {
-		t.Logf("SUCCESS: DeleteAccount returned error: %v", err)
+	accountClient.FetchById("../../admin")
+	accountClient.FetchById("a#b")
+	accountClient.DeleteAccount("x?version=0&y", 3)
+	accountClient.DeleteAccount("a/b", 1)
+
+	expectedURIs := []string{
+		UNIT_ACCOUNTS_API_BASE + "/..%2F..%2Fadmin",
+		UNIT_ACCOUNTS_API_BASE + "/a%23b",
+		UNIT_ACCOUNTS_API_BASE + "/x%3Fversion=0&y?version=3",
+		UNIT_ACCOUNTS_API_BASE + "/a%2Fb?version=1",
+	}
+	if !reflect.DeepEqual(*requestURIs, expectedURIs) {
+		t.Errorf("FAILED: expected escaped request URIs %v, got %v", expectedURIs, *requestURIs)
+	} else {
+		t.Logf("SUCCESS: account IDs stayed in a single path segment: %v", *requestURIs)
 	}
 }
 
-func TestAccountClient_DeleteAccount_NotFound(t *testing.T) {
-	accountClient, multiplexer, close := prepareTestAccountClient()
-	defer close()
+func TestAccountClient_PageInjection(t *testing.T) {
+	accountClient, requestURIs, closeServer := prepareTestAccountClientRecordingUris()
+	defer closeServer()
 
-	muxUrl := UNIT_ACCOUNTS_API_BASE + "/" + WRONG_ACCOUNT_ID
-	multiplexer.HandleFunc(muxUrl, func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNotFound)
-	})
+	accountClient.ListAccount(&AccountParams{Number: "1&page[size]=1000#x", Size: 2})
+	expectedURI := UNIT_ACCOUNTS_API_BASE + "?page%5Bnumber%5D=1%26page%5Bsize%5D%3D1000%23x&page%5Bsize%5D=2"
+	if len(*requestURIs) != 1 || (*requestURIs)[0] != expectedURI {
+		t.Errorf("FAILED: expected request URI %v, got %v", expectedURI, *requestURIs)
+	}
+}
 
-	res, err := accountClient.DeleteAccount(WRONG_ACCOUNT_ID, 0)
-	if res.StatusCode == 404 {
-		t.Logf("SUCCESS: status code expected %v, got %v\n", 404, res.StatusCode)
-	} else if err != nil {
-		t.Errorf("FAILED: Error while calling DeleteAccount: %v\n", err)
+func TestAccountClient_InvalidId(t *testing.T) {
+	accountClient, requestURIs, closeServer := prepareTestAccountClientRecordingUris()
+	defer closeServer()
+
+	for _, id := range []string{"", ".", ".."} {
+		if _, _, _, err := accountClient.FetchById(id); !errors.Is(err, ErrInvalidAccountID) {
+			t.Errorf("FAILED: expected FetchById(%q) to fail with ErrInvalidAccountID, got %v", id, err)
+		}
+		if _, err := accountClient.DeleteAccount(id, 0); !errors.Is(err, ErrInvalidAccountID) {
+			t.Errorf("FAILED: expected DeleteAccount(%q) to fail with ErrInvalidAccountID, got %v", id, err)
+		}
+	}
+	if len(*requestURIs) != 0 {
+		t.Errorf("FAILED: expected no requests for invalid IDs, got %v", *requestURIs)
+	}
+}
+
+func TestAccountClient_RequireUUID(t *testing.T) {
+	accountClient, requestURIs, closeServer := prepareTestAccountClientRecordingUris()
+	defer closeServer()
+	accountClient.RequireUUID = true
+
+	if _, _, _, err := accountClient.FetchById(WRONG_ACCOUNT_ID); !errors.Is(err, ErrInvalidAccountID) {
+		t.Errorf("FAILED: expected FetchById to reject %q, got %v", WRONG_ACCOUNT_ID, err)
+	}
+	if _, err := accountClient.DeleteAccount(SINGLE_ACCOUNT_ID+"/..", 0); !errors.Is(err, ErrInvalidAccountID) {
+		t.Errorf("FAILED: expected DeleteAccount to reject a suffixed UUID, got %v", err)
+	}
+	if _, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID); err != nil {
+		t.Errorf("FAILED: expected FetchById to accept a UUID, got %v", err)
+	}
+	if len(*requestURIs) != 1 {
+		t.Errorf("FAILED: expected only the UUID to be requested, got %v", *requestURIs)
 	} else {
-		t.Errorf("FAILED: status code expected %v, got %v\n", 404, res.StatusCode)
+		t.Logf("SUCCESS: only UUIDs were requested: %v", *requestURIs)
 	}
 }
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of accounts listed per page while sweeping when the sweep setting has no page size.
+const SWEEP_PAGE_SIZE_DEFAULT = 100
+
+// Registers functions to run when a test finishes. It is satisfied by testing.TB.
+type CleanupRegistrar interface {
+	Cleanup(cleanup func())
+	Logf(format string, args ...interface{})
+}
+
+// Account client recording every account created through it, and deleting the accounts which are
+// still there when the test it was created for finishes.
+type TrackingAccountClient struct {
+	*AccountClient
+	mutex    sync.Mutex
+	accounts []AccountVersion
+}
+
+// Selects the stale test accounts deleted by SweepAccounts.
+type SweepSetting struct {
+	// Accounts whose organisation ID starts with the prefix are deleted. Must not be empty.
+	OrganisationIDPrefix string
+	// Only accounts created longer than this ago are deleted, all matching accounts when zero.
+	OlderThan time.Duration
+	PageSize  int
+}
+
+// Creates a tracking account client using the account client, registering the deletion of the
+// tracked accounts as cleanup. Failed deletions are logged rather than failing the test.
+func NewTrackingAccountClient(accountClient *AccountClient, registrar CleanupRegistrar) *TrackingAccountClient {
+	if accountClient == nil {
+		accountClient = NewAccountClient(nil)
+	}
+	trackingClient := &TrackingAccountClient{AccountClient: accountClient}
+	registrar.Cleanup(func() {
+		if err := trackingClient.DeleteTracked(context.Background()); err != nil {
+			registrar.Logf("deleting tracked accounts: %v", err)
+		}
+	})
+	return trackingClient
+}
+
+// Creates a bank account with provided account data payload and tracks it.
+// Returns account data, links and http response.
+func (trackingClient *TrackingAccountClient) CreateAccount(payload *AccountData) (*AccountData, *Links, *http.Response, error) {
+	return trackingClient.CreateAccountWithContext(context.Background(), payload)
+}
+
+// Creates a bank account using context with provided account data payload and tracks it.
+// Returns account data, links and http response.
+func (trackingClient *TrackingAccountClient) CreateAccountWithContext(ctx context.Context, payload *AccountData) (*AccountData, *Links, *http.Response, error) {
+	account, links, httpResponse, err := trackingClient.AccountClient.CreateAccountWithContext(ctx, payload)
+	if err == nil {
+		trackingClient.track(account)
+	}
+	return account, links, httpResponse, err
+}
+
+// Creates accounts with the provided payloads in parallel and tracks the created ones.
+// Returns a result per payload.
+func (trackingClient *TrackingAccountClient) BulkCreate(payloads []*AccountData, setting *BulkSetting) ([]BulkResult, error) {
+	return trackingClient.BulkCreateWithContext(context.Background(), payloads, setting)
+}
+
+// Creates accounts using context with the provided payloads in parallel and tracks the created ones.
+// Returns a result per payload.
+func (trackingClient *TrackingAccountClient) BulkCreateWithContext(ctx context.Context, payloads []*AccountData, setting *BulkSetting) ([]BulkResult, error) {
+	results, err := trackingClient.AccountClient.BulkCreateWithContext(ctx, payloads, setting)
+	for _, result := range results {
+		if result.Err == nil && result.Account != nil {
+			trackingClient.track(result.Account)
+		}
+	}
+	return results, err
+}
+
+// Deletes a account using the account ID and version number, and stops tracking it once deleted.
+// Returns http response.
+func (trackingClient *TrackingAccountClient) DeleteAccount(id string, version int) (*http.Response, error) {
+	return trackingClient.DeleteAccountWithContext(context.Background(), id, version)
+}
+
+// Deletes a account using context, the account ID and version number, and stops tracking it once deleted.
+// Returns http response.
+func (trackingClient *TrackingAccountClient) DeleteAccountWithContext(ctx context.Context, id string, version int) (*http.Response, error) {
+	httpResponse, err := trackingClient.AccountClient.DeleteAccountWithContext(ctx, id, version)
+	if err == nil && httpResponse != nil && (httpResponse.StatusCode < 300 || httpResponse.StatusCode == http.StatusNotFound) {
+		trackingClient.untrack(id)
+	}
+	return httpResponse, err
+}
+
+// Returns the tracked accounts which have not been deleted, in creation order.
+func (trackingClient *TrackingAccountClient) Tracked() []AccountVersion {
+	trackingClient.mutex.Lock()
+	defer trackingClient.mutex.Unlock()
+	return append([]AccountVersion{}, trackingClient.accounts...)
+}
+
+// Deletes the tracked accounts newest first, using their current version when it has changed.
+// Accounts which are already gone count as deleted. Returns a bulk error when any deletion failed.
+func (trackingClient *TrackingAccountClient) DeleteTracked(ctx context.Context) error {
+	accounts := trackingClient.Tracked()
+	bulkError := &BulkError{Total: len(accounts)}
+	for i := len(accounts) - 1; i >= 0; i-- {
+		if err := trackingClient.AccountClient.deleteCurrentVersion(ctx, accounts[i]); err != nil {
+			bulkError.Failed++
+			if bulkError.First == nil {
+				bulkError.First = err
+			}
+			continue
+		}
+		trackingClient.untrack(accounts[i].ID)
+	}
+	if bulkError.Failed > 0 {
+		return bulkError
+	}
+	return nil
+}
+
+func (trackingClient *TrackingAccountClient) track(account *AccountData) {
+	if account == nil || account.ID == "" {
+		return
+	}
+	tracked := AccountVersion{ID: account.ID}
+	if account.Version != nil {
+		tracked.Version = int(*account.Version)
+	}
+	trackingClient.mutex.Lock()
+	defer trackingClient.mutex.Unlock()
+	trackingClient.accounts = append(trackingClient.accounts, tracked)
+}
+
+func (trackingClient *TrackingAccountClient) untrack(id string) {
+	trackingClient.mutex.Lock()
+	defer trackingClient.mutex.Unlock()
+	for i, tracked := range trackingClient.accounts {
+		if tracked.ID == id {
+			trackingClient.accounts = append(trackingClient.accounts[:i], trackingClient.accounts[i+1:]...)
+			return
+		}
+	}
+}
+
+// Deletes the stale test accounts selected by the sweep setting. Every page is listed before
+// anything is deleted, so deletions do not shift accounts between pages.
+// Returns the accounts deleted, along with a bulk error when any deletion failed.
+func (accountClient *AccountClient) SweepAccounts(ctx context.Context, setting *SweepSetting) ([]AccountVersion, error) {
+	if setting == nil || setting.OrganisationIDPrefix == "" {
+		return nil, errors.New("sweeping accounts requires an organisation ID prefix")
+	}
+	pageSize := setting.PageSize
+	if pageSize <= 0 {
+		pageSize = SWEEP_PAGE_SIZE_DEFAULT
+	}
+
+	var stale []AccountVersion
+	cutoff := time.Now().Add(-setting.OlderThan)
+	for number := 0; ; number++ {
+		accounts, links, _, err := accountClient.ListAccountWithContext(ctx, &AccountParams{Number: strconv.Itoa(number), Size: pageSize})
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range accounts {
+			if !strings.HasPrefix(account.OrganisationID, setting.OrganisationIDPrefix) {
+				continue
+			}
+			if setting.OlderThan > 0 && (account.CreatedOn == nil || account.CreatedOn.After(cutoff)) {
+				continue
+			}
+			candidate := AccountVersion{ID: account.ID}
+			if account.Version != nil {
+				candidate.Version = int(*account.Version)
+			}
+			stale = append(stale, candidate)
+		}
+		if len(accounts) < pageSize || links == nil || links.Next == "" {
+			break
+		}
+	}
+
+	results, err := runBulk(ctx, len(stale), &BulkSetting{ContinueOnError: true}, func(index int) string { return stale[index].ID }, func(ctx context.Context, index int) BulkResult {
+		return BulkResult{ID: stale[index].ID, Err: accountClient.deleteCurrentVersion(ctx, stale[index])}
+	})
+	var deleted []AccountVersion
+	for i, result := range results {
+		if result.Err == nil {
+			deleted = append(deleted, stale[i])
+		}
+	}
+	return deleted, err
+}
+
+// Deletes the account, fetching its current version and trying again when the version has changed.
+// An account which does not exist counts as deleted.
+func (accountClient *AccountClient) deleteCurrentVersion(ctx context.Context, account AccountVersion) error {
+	httpResponse, err := accountClient.DeleteAccountWithContext(ctx, account.ID, account.Version)
+	if httpResponse != nil && httpResponse.StatusCode == http.StatusConflict {
+		current, _, fetchResponse, fetchErr := accountClient.FetchByIdWithContext(ctx, account.ID)
+		if fetchResponse != nil && fetchResponse.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		if fetchErr != nil {
+			return fetchErr
+		}
+		version := 0
+		if current.Version != nil {
+			version = int(*current.Version)
+		}
+		httpResponse, err = accountClient.DeleteAccountWithContext(ctx, account.ID, version)
+	}
+	if httpResponse != nil && httpResponse.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if httpResponse.StatusCode >= 300 {
+		return fmt.Errorf("deleting account %s: unexpected status %d", account.ID, httpResponse.StatusCode)
+	}
+	return nil
+}
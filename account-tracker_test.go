@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"form3/rest-client/accountapitest"
+)
+
+type recordingRegistrar struct {
+	cleanups []func()
+	logs     []string
+}
+
+func (registrar *recordingRegistrar) Cleanup(cleanup func()) {
+	registrar.cleanups = append(registrar.cleanups, cleanup)
+}
+
+func (registrar *recordingRegistrar) Logf(format string, args ...interface{}) {
+	registrar.logs = append(registrar.logs, fmt.Sprintf(format, args...))
+}
+
+// Runs the registered cleanups last first, as testing.T does.
+func (registrar *recordingRegistrar) finish() {
+	for i := len(registrar.cleanups) - 1; i >= 0; i-- {
+		registrar.cleanups[i]()
+	}
+}
+
+func prepareTestFakeAccountClient() (*AccountClient, *accountapitest.Server) {
+	server := accountapitest.NewServer()
+	httpClient := NewHttpClient(&ClientSetting{BaseURL: server.BaseURL, Timeout: INTEGRATION_TIME_OUT})
+	return NewAccountClient(httpClient), server
+}
+
+func TestTrackingAccountClient_CleanupDeletesCreated(t *testing.T) {
+	accountClient, server := prepareTestFakeAccountClient()
+	defer server.Close()
+	registrar := &recordingRegistrar{}
+	trackingClient := NewTrackingAccountClient(accountClient, registrar)
+
+	created, _, _, err := trackingClient.CreateAccount(populateAccountDataIntegration())
+	if err != nil {
+		t.Fatalf("FAILED: CreateAccount returned error: %v", err)
+	}
+	trackingClient.CreateAccount(populateAccountDataIntegration())
+	trackingClient.BulkCreate([]*AccountData{populateAccountDataIntegration(), populateAccountDataIntegration()}, nil)
+	trackingClient.DeleteAccount(created.ID, int(*created.Version))
+
+	if tracked := trackingClient.Tracked(); len(tracked) != 3 {
+		t.Errorf("FAILED: expected 3 tracked accounts, got %v", tracked)
+	}
+	registrar.finish()
+	if remaining := server.Handler.Accounts(); len(remaining) != 0 {
+		t.Errorf("FAILED: expected cleanup to delete every account, %d remain", len(remaining))
+	} else if len(registrar.logs) != 0 {
+		t.Errorf("FAILED: expected no cleanup failures, got %v", registrar.logs)
+	} else {
+		t.Logf("SUCCESS: cleanup deleted the tracked accounts")
+	}
+}
+
+func TestTrackingAccountClient_TestingCleanup(t *testing.T) {
+	accountClient, server := prepareTestFakeAccountClient()
+	defer server.Close()
+
+	t.Run("create", func(t *testing.T) {
+		trackingClient := NewTrackingAccountClient(accountClient, t)
+		trackingClient.CreateAccount(populateAccountDataIntegration())
+		if len(server.Handler.Accounts()) != 1 {
+			t.Errorf("FAILED: expected the account to exist during the test")
+		}
+	})
+	if remaining := server.Handler.Accounts(); len(remaining) != 0 {
+		t.Errorf("FAILED: expected t.Cleanup to delete the account, %d remain", len(remaining))
+	}
+}
+
+func TestTrackingAccountClient_CleanupUsesCurrentVersion(t *testing.T) {
+	var deletes []string
+	multiplexer := http.NewServeMux()
+	server := httptest.NewServer(multiplexer)
+	defer server.Close()
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	})
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE+"/"+SINGLE_ACCOUNT_ID, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			fmt.Fprint(w, strings.Replace(SINGLE_ACCOUNT_MOCK_RESPONSE, `"version": 0`, `"version": 2`, 1))
+		case r.URL.Query().Get("version") == "2":
+			deletes = append(deletes, r.URL.RawQuery)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			deletes = append(deletes, r.URL.RawQuery)
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"error_message": "invalid version"}`)
+		}
+	})
+	httpClient := NewHttpClient(&ClientSetting{BaseURL: server.URL + UNIT_ACCOUNTS_API_BASE, Timeout: INTEGRATION_TIME_OUT})
+	registrar := &recordingRegistrar{}
+	trackingClient := NewTrackingAccountClient(NewAccountClient(httpClient), registrar)
+
+	trackingClient.CreateAccount(populateSingleAccountDataUnitTest())
+	registrar.finish()
+	if strings.Join(deletes, ",") != "version=0,version=2" {
+		t.Errorf("FAILED: expected delete to be retried with the current version, got %v", deletes)
+	}
+	if len(registrar.logs) != 0 || len(trackingClient.Tracked()) != 0 {
+		t.Errorf("FAILED: expected the account to be deleted, got logs %v", registrar.logs)
+	}
+}
+
+func TestTrackingAccountClient_CleanupLogsFailures(t *testing.T) {
+	multiplexer := http.NewServeMux()
+	server := httptest.NewServer(multiplexer)
+	defer server.Close()
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	})
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE+"/"+SINGLE_ACCOUNT_ID, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	httpClient := NewHttpClient(&ClientSetting{BaseURL: server.URL + UNIT_ACCOUNTS_API_BASE, Timeout: INTEGRATION_TIME_OUT})
+	registrar := &recordingRegistrar{}
+	trackingClient := NewTrackingAccountClient(NewAccountClient(httpClient), registrar)
+
+	trackingClient.CreateAccount(populateSingleAccountDataUnitTest())
+	registrar.finish()
+	if len(registrar.logs) != 1 || !strings.Contains(registrar.logs[0], "unexpected status 500") {
+		t.Errorf("FAILED: expected the failed deletion to be logged, got %v", registrar.logs)
+	}
+	if len(trackingClient.Tracked()) != 1 {
+		t.Errorf("FAILED: expected the account to stay tracked after a failed deletion")
+	}
+}
+
+func TestSweepAccounts(t *testing.T) {
+	accountClient, server := prepareTestFakeAccountClient()
+	defer server.Close()
+
+	server.Handler.Now = func() time.Time { return time.Now().Add(-time.Hour) }
+	var stale []string
+	for i := 0; i < 3; i++ {
+		account, _, _, _ := accountClient.CreateAccount(populateAccountDataIntegration())
+		stale = append(stale, account.ID)
+	}
+	other := populateAccountDataIntegration()
+	other.OrganisationID = uuid()
+	accountClient.CreateAccount(other)
+	server.Handler.Now = nil
+	accountClient.CreateAccount(populateAccountDataIntegration())
+
+	if _, err := accountClient.SweepAccounts(context.Background(), &SweepSetting{}); err == nil {
+		t.Errorf("FAILED: expected a sweep without prefix to be rejected")
+	}
+	deleted, err := accountClient.SweepAccounts(context.Background(), &SweepSetting{
+		OrganisationIDPrefix: INTEGRATION_ORGANISATION_ID_PREFIX,
+		OlderThan:            30 * time.Minute,
+		PageSize:             2,
+	})
+	if err != nil {
+		t.Fatalf("FAILED: SweepAccounts returned error: %v", err)
+	}
+	if len(deleted) != len(stale) {
+		t.Errorf("FAILED: expected %d stale accounts to be deleted, got %v", len(stale), deleted)
+	}
+	if remaining := server.Handler.Accounts(); len(remaining) != 2 {
+		t.Errorf("FAILED: expected the recent and foreign accounts to remain, got %d", len(remaining))
+	} else {
+		t.Logf("SUCCESS: swept %d stale accounts", len(deleted))
+	}
+}
@@ -0,0 +1,155 @@
+package accountapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Account as stored by the fake API. Attributes are kept as sent so they are returned unchanged.
+type Account struct {
+	Attributes     json.RawMessage `json:"attributes,omitempty"`
+	CreatedOn      time.Time       `json:"created_on"`
+	ID             string          `json:"id"`
+	ModifiedOn     time.Time       `json:"modified_on"`
+	OrganisationID string          `json:"organisation_id"`
+	Type           string          `json:"type"`
+	Version        int64           `json:"version"`
+}
+
+type accountAttributes struct {
+	AccountClassification *string  `json:"account_classification"`
+	AccountNumber         string   `json:"account_number"`
+	AlternativeNames      []string `json:"alternative_names"`
+	BankID                string   `json:"bank_id"`
+	BankIDCode            string   `json:"bank_id_code"`
+	BaseCurrency          string   `json:"base_currency"`
+	Bic                   string   `json:"bic"`
+	Country               *string  `json:"country"`
+	Iban                  string   `json:"iban"`
+	Name                  []string `json:"name"`
+	Status                *string  `json:"status"`
+}
+
+type createRequest struct {
+	Data *struct {
+		Attributes     json.RawMessage `json:"attributes"`
+		ID             string          `json:"id"`
+		OrganisationID string          `json:"organisation_id"`
+		Type           string          `json:"type"`
+	} `json:"data"`
+}
+
+const (
+	MAX_NAMES             = 4
+	MAX_ALTERNATIVE_NAMES = 3
+	MAX_NAME_LENGTH       = 140
+)
+
+var (
+	ACCOUNT_CLASSIFICATIONS = []string{"Personal", "Business"}
+	ACCOUNT_STATUSES        = []string{"pending", "confirmed", "closed"}
+)
+
+var (
+	uuidPattern          = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	countryPattern       = regexp.MustCompile(`^[A-Z]{2}$`)
+	currencyPattern      = regexp.MustCompile(`^[A-Z]{3}$`)
+	bankIDPattern        = regexp.MustCompile(`^[A-Z0-9]{0,16}$`)
+	bankIDCodePattern    = regexp.MustCompile(`^[A-Z]{0,16}$`)
+	bicPattern           = regexp.MustCompile(`^([A-Z]{6}[A-Z0-9]{2}|[A-Z]{6}[A-Z0-9]{5})$`)
+	ibanPattern          = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{0,64}$`)
+	accountNumberPattern = regexp.MustCompile(`^[A-Z0-9]{0,64}$`)
+)
+
+// Returns whether the value has the shape of a UUID.
+func isUUID(value string) bool {
+	return uuidPattern.MatchString(value)
+}
+
+// Validates a create request the way the account API does.
+// Returns the validation failures, empty when the request is valid.
+func validateCreateRequest(request *createRequest) []string {
+	if request.Data == nil {
+		return []string{"data in body is required"}
+	}
+	var failures []string
+	data := request.Data
+	if data.ID == "" {
+		failures = append(failures, "id in body is required")
+	} else if !isUUID(data.ID) {
+		failures = append(failures, fmt.Sprintf("id in body must be of type uuid: %q", data.ID))
+	}
+	if data.OrganisationID == "" {
+		failures = append(failures, "organisation_id in body is required")
+	} else if !isUUID(data.OrganisationID) {
+		failures = append(failures, fmt.Sprintf("organisation_id in body must be of type uuid: %q", data.OrganisationID))
+	}
+	if data.Type != "accounts" {
+		failures = append(failures, `type in body should be one of [accounts]`)
+	}
+	if len(data.Attributes) == 0 || string(data.Attributes) == "null" {
+		return append(failures, "attributes in body is required")
+	}
+
+	attributes := accountAttributes{}
+	if err := json.Unmarshal(data.Attributes, &attributes); err != nil {
+		return append(failures, "attributes in body must be an object")
+	}
+	if attributes.Country == nil {
+		failures = append(failures, "country in body is required")
+	} else if !countryPattern.MatchString(*attributes.Country) {
+		failures = append(failures, fmt.Sprintf("country in body should match '%s'", countryPattern))
+	}
+	if len(attributes.Name) == 0 {
+		failures = append(failures, "name in body is required")
+	} else if len(attributes.Name) > MAX_NAMES {
+		failures = append(failures, fmt.Sprintf("name in body should have at most %d items", MAX_NAMES))
+	}
+	for i, name := range attributes.Name {
+		if len(name) > MAX_NAME_LENGTH {
+			failures = append(failures, fmt.Sprintf("name.%d in body should be at most %d chars long", i, MAX_NAME_LENGTH))
+		}
+	}
+	if len(attributes.AlternativeNames) > MAX_ALTERNATIVE_NAMES {
+		failures = append(failures, fmt.Sprintf("alternative_names in body should have at most %d items", MAX_ALTERNATIVE_NAMES))
+	}
+	failures = appendPatternFailure(failures, "account_number", attributes.AccountNumber, accountNumberPattern)
+	failures = appendPatternFailure(failures, "bank_id", attributes.BankID, bankIDPattern)
+	failures = appendPatternFailure(failures, "bank_id_code", attributes.BankIDCode, bankIDCodePattern)
+	failures = appendPatternFailure(failures, "base_currency", attributes.BaseCurrency, currencyPattern)
+	failures = appendPatternFailure(failures, "bic", attributes.Bic, bicPattern)
+	failures = appendPatternFailure(failures, "iban", attributes.Iban, ibanPattern)
+	if attributes.AccountClassification != nil {
+		failures = appendEnumFailure(failures, "account_classification", *attributes.AccountClassification, ACCOUNT_CLASSIFICATIONS)
+	}
+	if attributes.Status != nil {
+		failures = appendEnumFailure(failures, "status", *attributes.Status, ACCOUNT_STATUSES)
+	}
+	return failures
+}
+
+// Appends a failure when the optional value is set but does not match the pattern.
+func appendPatternFailure(failures []string, field string, value string, pattern *regexp.Regexp) []string {
+	if value == "" || pattern.MatchString(value) {
+		return failures
+	}
+	return append(failures, fmt.Sprintf("%s in body should match '%s'", field, pattern))
+}
+
+// Appends a failure when the value is not one of the allowed values.
+func appendEnumFailure(failures []string, field string, value string, allowed []string) []string {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return failures
+		}
+	}
+	return append(failures, fmt.Sprintf("%s in body should be one of [%s]", field, strings.Join(allowed, " ")))
+}
+
+// Formats validation failures like the account API error message.
+func validationMessage(failures []string) string {
+	return "validation failure list:\n" + strings.Join(failures, "\n")
+}
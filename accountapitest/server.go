@@ -0,0 +1,278 @@
+// Package accountapitest provides an in-memory implementation of the form3 organisation accounts
+// API for tests which should not depend on the docker-compose stack.
+package accountapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ACCOUNTS_PATH     = "/v1/organisation/accounts"
+	PAGE_SIZE_DEFAULT = 100
+	PAGE_SIZE_MAX     = 1000
+)
+
+const (
+	DUPLICATE_ACCOUNT_MESSAGE = "Account cannot be created as it violates a duplicate constraint"
+	INVALID_UUID_MESSAGE      = "id is not a valid uuid"
+	INVALID_VERSION_MESSAGE   = "invalid version"
+	INVALID_PAGE_MESSAGE      = "invalid page parameters"
+)
+
+// Stateful http handler serving the organisation accounts API from memory. It is safe for concurrent use.
+type Handler struct {
+	mutex    sync.Mutex
+	accounts map[string]*Account
+	order    []string
+	// Returns the time stamped on created accounts, time.Now when nil.
+	Now func() time.Time
+}
+
+// Test server running a handler, with BaseURL pointing at the accounts resource.
+type Server struct {
+	*httptest.Server
+	Handler *Handler
+	BaseURL string
+}
+
+type responseBody struct {
+	Data  interface{} `json:"data"`
+	Links *links      `json:"links,omitempty"`
+}
+
+type links struct {
+	First string `json:"first,omitempty"`
+	Last  string `json:"last,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Self  string `json:"self,omitempty"`
+}
+
+type errorBody struct {
+	ErrorMessage string `json:"error_message"`
+}
+
+// Creates an empty handler.
+func NewHandler() *Handler {
+	return &Handler{accounts: map[string]*Account{}}
+}
+
+// Starts a test server with an empty handler. Callers must close the server.
+func NewServer() *Server {
+	handler := NewHandler()
+	server := httptest.NewServer(handler)
+	return &Server{Server: server, Handler: handler, BaseURL: server.URL + ACCOUNTS_PATH}
+}
+
+// Serves the accounts resource, answering other paths with 404.
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == ACCOUNTS_PATH {
+		switch r.Method {
+		case http.MethodGet:
+			handler.list(w, r)
+		case http.MethodPost:
+			handler.create(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, ACCOUNTS_PATH+"/")
+	if id == r.URL.Path || strings.Contains(id, "/") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		handler.fetch(w, id)
+	case http.MethodDelete:
+		handler.delete(w, r, id)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Returns copies of the stored accounts in creation order.
+func (handler *Handler) Accounts() []Account {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	accounts := make([]Account, 0, len(handler.order))
+	for _, id := range handler.order {
+		accounts = append(accounts, *handler.accounts[id])
+	}
+	return accounts
+}
+
+// Removes every stored account.
+func (handler *Handler) Reset() {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	handler.accounts = map[string]*Account{}
+	handler.order = nil
+}
+
+// Creates an account, rejecting invalid payloads and duplicate IDs.
+func (handler *Handler) create(w http.ResponseWriter, r *http.Request) {
+	request := &createRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if failures := validateCreateRequest(request); len(failures) > 0 {
+		writeError(w, http.StatusBadRequest, validationMessage(failures))
+		return
+	}
+
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	if _, exists := handler.accounts[request.Data.ID]; exists {
+		writeError(w, http.StatusConflict, DUPLICATE_ACCOUNT_MESSAGE)
+		return
+	}
+	now := handler.now().UTC()
+	account := &Account{
+		Attributes:     request.Data.Attributes,
+		CreatedOn:      now,
+		ID:             request.Data.ID,
+		ModifiedOn:     now,
+		OrganisationID: request.Data.OrganisationID,
+		Type:           request.Data.Type,
+	}
+	handler.accounts[account.ID] = account
+	handler.order = append(handler.order, account.ID)
+	writeBody(w, http.StatusCreated, responseBody{Data: account, Links: &links{Self: ACCOUNTS_PATH + "/" + account.ID}})
+}
+
+// Returns a single account.
+func (handler *Handler) fetch(w http.ResponseWriter, id string) {
+	if !isUUID(id) {
+		writeError(w, http.StatusBadRequest, INVALID_UUID_MESSAGE)
+		return
+	}
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	account, ok := handler.accounts[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("record %s does not exist", id))
+		return
+	}
+	writeBody(w, http.StatusOK, responseBody{Data: account, Links: &links{Self: ACCOUNTS_PATH + "/" + id}})
+}
+
+// Returns a page of accounts in creation order with links to the neighbouring pages.
+func (handler *Handler) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	size := PAGE_SIZE_DEFAULT
+	if value := query.Get("page[size]"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > PAGE_SIZE_MAX {
+			writeError(w, http.StatusBadRequest, INVALID_PAGE_MESSAGE)
+			return
+		}
+		size = parsed
+	}
+
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	last := 0
+	if len(handler.order) > 0 {
+		last = (len(handler.order) - 1) / size
+	}
+	number := 0
+	switch value := query.Get("page[number]"); value {
+	case "", "first":
+	case "last":
+		number = last
+	default:
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, INVALID_PAGE_MESSAGE)
+			return
+		}
+		number = parsed
+	}
+
+	accounts := []*Account{}
+	for i := number * size; i < len(handler.order) && i < (number+1)*size; i++ {
+		accounts = append(accounts, handler.accounts[handler.order[i]])
+	}
+	pageLinks := &links{
+		First: pageLink("first", size),
+		Last:  pageLink("last", size),
+		Self:  pageLink(strconv.Itoa(number), size),
+	}
+	if number < last {
+		pageLinks.Next = pageLink(strconv.Itoa(number+1), size)
+	}
+	if number > 0 {
+		pageLinks.Prev = pageLink(strconv.Itoa(number-1), size)
+	}
+	writeBody(w, http.StatusOK, responseBody{Data: accounts, Links: pageLinks})
+}
+
+// Deletes an account when the version matches the stored version.
+func (handler *Handler) delete(w http.ResponseWriter, r *http.Request, id string) {
+	if !isUUID(id) {
+		writeError(w, http.StatusBadRequest, INVALID_UUID_MESSAGE)
+		return
+	}
+	version, err := strconv.ParseInt(r.URL.Query().Get("version"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid version number")
+		return
+	}
+
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	account, ok := handler.accounts[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if account.Version != version {
+		writeError(w, http.StatusConflict, INVALID_VERSION_MESSAGE)
+		return
+	}
+	delete(handler.accounts, id)
+	for i, candidate := range handler.order {
+		if candidate == id {
+			handler.order = append(handler.order[:i], handler.order[i+1:]...)
+			break
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *Handler) now() time.Time {
+	if handler.Now != nil {
+		return handler.Now()
+	}
+	return time.Now()
+}
+
+// Builds a page link in the format used by the account API.
+func pageLink(number string, size int) string {
+	query := url.Values{}
+	query.Set("page[number]", number)
+	query.Set("page[size]", strconv.Itoa(size))
+	return ACCOUNTS_PATH + "?" + query.Encode()
+}
+
+func writeBody(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeBody(w, status, errorBody{ErrorMessage: message})
+}
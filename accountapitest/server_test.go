@@ -0,0 +1,171 @@
+package accountapitest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	client "form3/rest-client"
+)
+
+func prepareTestServerClient() (*Server, *client.AccountClient) {
+	server := NewServer()
+	httpClient := client.NewHttpClient(&client.ClientSetting{BaseURL: server.BaseURL, Timeout: 5000})
+	return server, client.NewAccountClient(httpClient)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func newAccount() *client.AccountData {
+	country := "GB"
+	return &client.AccountData{
+		ID:             newID(),
+		OrganisationID: newID(),
+		Type:           "accounts",
+		Attributes: &client.AccountAttributes{
+			Country:      &country,
+			BankID:       "400300",
+			BankIDCode:   "GBDSC",
+			Bic:          "NWBKGB22",
+			BaseCurrency: "GBP",
+			Name:         []string{"Jane Doe"},
+		},
+	}
+}
+
+func TestServer_CreateAndFetch(t *testing.T) {
+	server, accountClient := prepareTestServerClient()
+	defer server.Close()
+
+	payload := newAccount()
+	created, links, res, err := accountClient.CreateAccount(payload)
+	if err != nil || res.StatusCode != http.StatusCreated {
+		t.Fatalf("FAILED: CreateAccount returned %v %v", res, err)
+	}
+	if created.ID != payload.ID || *created.Version != 0 || created.CreatedOn == nil || links.Self != ACCOUNTS_PATH+"/"+payload.ID {
+		t.Errorf("FAILED: unexpected created account %+v %+v", created, links)
+	}
+
+	fetched, _, res, err := accountClient.FetchById(payload.ID)
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("FAILED: FetchById returned %v %v", res, err)
+	}
+	if fetched.Attributes.Name[0] != "Jane Doe" || *fetched.Attributes.Country != "GB" {
+		t.Errorf("FAILED: expected attributes to be stored unchanged, got %+v", fetched.Attributes)
+	}
+	t.Log("SUCCESS: account was created and fetched")
+}
+
+func TestServer_DuplicateAccount(t *testing.T) {
+	server, accountClient := prepareTestServerClient()
+	defer server.Close()
+
+	payload := newAccount()
+	accountClient.CreateAccount(payload)
+	_, _, res, err := accountClient.CreateAccount(payload)
+	if res == nil || res.StatusCode != http.StatusConflict || !strings.Contains(err.Error(), DUPLICATE_ACCOUNT_MESSAGE) {
+		t.Errorf("FAILED: expected 409 duplicate constraint error, got %v %v", res, err)
+	}
+}
+
+func TestServer_ValidationErrors(t *testing.T) {
+	server, accountClient := prepareTestServerClient()
+	defer server.Close()
+
+	payload := newAccount()
+	payload.ID = "abc"
+	payload.Attributes.Country = nil
+	payload.Attributes.Bic = "nope"
+	_, _, res, err := accountClient.CreateAccount(payload)
+	if res == nil || res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("FAILED: expected 400 response, got %v %v", res, err)
+	}
+	for _, expected := range []string{"validation failure list:", `id in body must be of type uuid: "abc"`, "country in body is required", "bic in body should match"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("FAILED: expected %q in error, got %v", expected, err)
+		}
+	}
+	if len(server.Handler.Accounts()) != 0 {
+		t.Errorf("FAILED: expected invalid accounts not to be stored")
+	}
+}
+
+func TestServer_FetchErrors(t *testing.T) {
+	server, accountClient := prepareTestServerClient()
+	defer server.Close()
+
+	if _, _, res, _ := accountClient.FetchById("abc"); res == nil || res.StatusCode != http.StatusBadRequest {
+		t.Errorf("FAILED: expected 400 for an invalid id, got %v", res)
+	}
+	id := newID()
+	_, _, res, err := accountClient.FetchById(id)
+	if res == nil || res.StatusCode != http.StatusNotFound || !strings.Contains(err.Error(), "record "+id+" does not exist") {
+		t.Errorf("FAILED: expected 404 for an unknown id, got %v %v", res, err)
+	}
+}
+
+func TestServer_Pagination(t *testing.T) {
+	server, accountClient := prepareTestServerClient()
+	defer server.Close()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		payload := newAccount()
+		ids = append(ids, payload.ID)
+		accountClient.CreateAccount(payload)
+	}
+
+	accounts, links, _, err := accountClient.ListAccount(&client.AccountParams{Number: "1", Size: 2})
+	if err != nil || len(accounts) != 2 || accounts[0].ID != ids[2] || accounts[1].ID != ids[3] {
+		t.Fatalf("FAILED: expected the second page of two accounts, got %v %v", accounts, err)
+	}
+	if links.Next != ACCOUNTS_PATH+"?page%5Bnumber%5D=2&page%5Bsize%5D=2" || links.Prev != ACCOUNTS_PATH+"?page%5Bnumber%5D=0&page%5Bsize%5D=2" {
+		t.Errorf("FAILED: unexpected page links %+v", links)
+	}
+
+	accounts, links, _, _ = accountClient.ListAccount(&client.AccountParams{Number: "last", Size: 2})
+	if len(accounts) != 1 || accounts[0].ID != ids[4] || links.Next != "" {
+		t.Errorf("FAILED: expected the last page with one account and no next link, got %v %+v", accounts, links)
+	}
+
+	accounts, _, _, _ = accountClient.ListAccount(nil)
+	if len(accounts) != 5 {
+		t.Errorf("FAILED: expected all accounts on the default page, got %d", len(accounts))
+	}
+}
+
+func TestServer_DeleteVersionCheck(t *testing.T) {
+	server, accountClient := prepareTestServerClient()
+	defer server.Close()
+
+	payload := newAccount()
+	accountClient.CreateAccount(payload)
+
+	res, err := accountClient.DeleteAccount(payload.ID, 1)
+	if res == nil || res.StatusCode != http.StatusConflict || !strings.Contains(err.Error(), INVALID_VERSION_MESSAGE) {
+		t.Errorf("FAILED: expected 409 for a wrong version, got %v %v", res, err)
+	}
+	if res, err := accountClient.DeleteAccount(payload.ID, 0); err != nil || res.StatusCode != http.StatusNoContent {
+		t.Errorf("FAILED: expected 204 for the right version, got %v %v", res, err)
+	}
+	if res, _ := accountClient.DeleteAccount(payload.ID, 0); res == nil || res.StatusCode != http.StatusNotFound {
+		t.Errorf("FAILED: expected 404 after delete, got %v", res)
+	}
+}
+
+func TestServer_Reset(t *testing.T) {
+	server, accountClient := prepareTestServerClient()
+	defer server.Close()
+
+	accountClient.CreateAccount(newAccount())
+	server.Handler.Reset()
+	if accounts, _, _, _ := accountClient.ListAccount(nil); len(accounts) != 0 {
+		t.Errorf("FAILED: expected no accounts after reset, got %d", len(accounts))
+	}
+}
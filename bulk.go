@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Number of operations a bulk call runs in parallel unless configured otherwise.
+const BULK_CONCURRENCY_DEFAULT = 8
+
+// Error of the operations which were not started because an earlier operation failed.
+var ErrBulkSkipped = errors.New("bulk operation skipped after an earlier failure")
+
+// Configures a bulk call. Requests still pass through the rate limiter, bulkhead and circuit
+// breaker of the http client.
+type BulkSetting struct {
+	// Number of operations run in parallel.
+	Concurrency int
+	// Runs every operation even when some fail, instead of stopping at the first failure.
+	ContinueOnError bool
+	// Called after every finished operation. Calls never overlap.
+	OnProgress func(progress BulkProgress)
+}
+
+type BulkProgress struct {
+	Completed int
+	Failed    int
+	Total     int
+}
+
+// Outcome of a single operation of a bulk call, at the same index as its input.
+type BulkResult struct {
+	ID           string
+	Account      *AccountData
+	HttpResponse *http.Response
+	Err          error
+}
+
+// Identifies an account to delete along with its version.
+type AccountVersion struct {
+	ID      string
+	Version int
+}
+
+// Error returned when operations of a bulk call failed. Details are in the bulk results.
+type BulkError struct {
+	Failed int
+	Total  int
+	First  error
+}
+
+// Creates accounts with the provided payloads in parallel.
+// Returns a result per payload.
+func (accountClient *AccountClient) BulkCreate(payloads []*AccountData, setting *BulkSetting) ([]BulkResult, error) {
+	return accountClient.BulkCreateWithContext(context.Background(), payloads, setting)
+}
+
+// Creates accounts using context with the provided payloads in parallel.
+// Returns a result per payload.
+func (accountClient *AccountClient) BulkCreateWithContext(ctx context.Context, payloads []*AccountData, setting *BulkSetting) ([]BulkResult, error) {
+	payloadId := func(index int) string {
+		if payloads[index] == nil {
+			return ""
+		}
+		return payloads[index].ID
+	}
+	return runBulk(ctx, len(payloads), setting, payloadId, func(ctx context.Context, index int) BulkResult {
+		result := BulkResult{ID: payloadId(index)}
+		result.Account, _, result.HttpResponse, result.Err = accountClient.CreateAccountWithContext(ctx, payloads[index])
+		return result
+	})
+}
+
+// Gets accounts by ID in parallel.
+// Returns a result per ID.
+func (accountClient *AccountClient) BulkFetch(ids []string, setting *BulkSetting) ([]BulkResult, error) {
+	return accountClient.BulkFetchWithContext(context.Background(), ids, setting)
+}
+
+// Gets accounts using context by ID in parallel.
+// Returns a result per ID.
+func (accountClient *AccountClient) BulkFetchWithContext(ctx context.Context, ids []string, setting *BulkSetting) ([]BulkResult, error) {
+	return runBulk(ctx, len(ids), setting, func(index int) string { return ids[index] }, func(ctx context.Context, index int) BulkResult {
+		result := BulkResult{ID: ids[index]}
+		result.Account, _, result.HttpResponse, result.Err = accountClient.FetchByIdWithContext(ctx, ids[index])
+		return result
+	})
+}
+
+// Deletes accounts by ID and version in parallel.
+// Returns a result per account.
+func (accountClient *AccountClient) BulkDelete(accounts []AccountVersion, setting *BulkSetting) ([]BulkResult, error) {
+	return accountClient.BulkDeleteWithContext(context.Background(), accounts, setting)
+}
+
+// Deletes accounts using context by ID and version in parallel.
+// Returns a result per account.
+func (accountClient *AccountClient) BulkDeleteWithContext(ctx context.Context, accounts []AccountVersion, setting *BulkSetting) ([]BulkResult, error) {
+	return runBulk(ctx, len(accounts), setting, func(index int) string { return accounts[index].ID }, func(ctx context.Context, index int) BulkResult {
+		result := BulkResult{ID: accounts[index].ID}
+		result.HttpResponse, result.Err = accountClient.DeleteAccountWithContext(ctx, accounts[index].ID, accounts[index].Version)
+		return result
+	})
+}
+
+// Runs total operations on a pool of workers. Without ContinueOnError no further operations are
+// started after the first failure, while the ones already running are allowed to finish.
+// Operations which are not started fail with ErrBulkSkipped, or the context error, under their input ID.
+// Returns the results in input order and a bulk error when any operation failed.
+func runBulk(ctx context.Context, total int, setting *BulkSetting, id func(index int) string, operation func(ctx context.Context, index int) BulkResult) ([]BulkResult, error) {
+	if setting == nil {
+		setting = &BulkSetting{}
+	}
+	concurrency := setting.Concurrency
+	if concurrency <= 0 {
+		concurrency = BULK_CONCURRENCY_DEFAULT
+	}
+	if concurrency > total {
+		concurrency = total
+	}
+
+	results := make([]BulkResult, total)
+	indexes := make(chan int)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var mutex sync.Mutex
+	progress := BulkProgress{Total: total}
+	bulkError := &BulkError{Total: total}
+
+	var workers sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range indexes {
+				result := operation(ctx, index)
+				results[index] = result
+
+				mutex.Lock()
+				progress.Completed++
+				if result.Err != nil {
+					progress.Failed++
+					bulkError.Failed++
+					if bulkError.First == nil {
+						bulkError.First = result.Err
+					}
+					if !setting.ContinueOnError {
+						stopOnce.Do(func() { close(stop) })
+					}
+				}
+				if setting.OnProgress != nil {
+					setting.OnProgress(progress)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	next := 0
+dispatch:
+	for ; next < total; next++ {
+		// A ready worker must not win over a failure which has already stopped the bulk call.
+		select {
+		case <-stop:
+			break dispatch
+		case <-ctx.Done():
+			break dispatch
+		default:
+		}
+		select {
+		case indexes <- next:
+		case <-stop:
+			break dispatch
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	workers.Wait()
+
+	for index := next; index < total; index++ {
+		results[index].ID = id(index)
+		results[index].Err = ErrBulkSkipped
+		if ctx.Err() != nil {
+			results[index].Err = ctx.Err()
+		}
+		bulkError.Failed++
+		if bulkError.First == nil {
+			bulkError.First = results[index].Err
+		}
+	}
+	if bulkError.Failed > 0 {
+		return results, bulkError
+	}
+	return results, nil
+}
+
+// Returns how many operations failed along with the first failure.
+func (bulkError *BulkError) Error() string {
+	return fmt.Sprintf("%d of %d bulk operations failed: %v", bulkError.Failed, bulkError.Total, bulkError.First)
+}
+
+// Returns the first failure.
+func (bulkError *BulkError) Unwrap() error {
+	return bulkError.First
+}
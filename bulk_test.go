@@ -0,0 +1,162 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func bulkAccountIds(count int) []string {
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = uuid()
+	}
+	return ids
+}
+
+func TestBulk_FetchRunsInParallel(t *testing.T) {
+	var inFlight, maxInFlight int32
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		id := strings.TrimPrefix(r.URL.Path, UNIT_ACCOUNTS_API_BASE+"/")
+		fmt.Fprint(w, strings.Replace(SINGLE_ACCOUNT_MOCK_RESPONSE, SINGLE_ACCOUNT_ID, id, -1))
+	})
+	defer closeServer()
+
+	ids := bulkAccountIds(20)
+	var progressCalls []BulkProgress
+	results, err := accountClient.BulkFetch(ids, &BulkSetting{
+		Concurrency: 4,
+		OnProgress: func(progress BulkProgress) {
+			progressCalls = append(progressCalls, progress)
+		},
+	})
+	if err != nil {
+		t.Fatalf("FAILED: BulkFetch returned error: %v", err)
+	}
+	for i, result := range results {
+		if result.Err != nil || result.Account == nil || result.Account.ID != ids[i] {
+			t.Errorf("FAILED: expected account %s at index %d, got %+v", ids[i], i, result)
+		}
+	}
+	if maxInFlight != 4 {
+		t.Errorf("FAILED: expected 4 requests in flight, got %d", maxInFlight)
+	}
+	if len(progressCalls) != 20 || progressCalls[19] != (BulkProgress{Completed: 20, Total: 20}) {
+		t.Errorf("FAILED: expected a progress call per account, got %v", progressCalls)
+	}
+	t.Log("SUCCESS: accounts were fetched in parallel")
+}
+
+func TestBulk_CreateFailFast(t *testing.T) {
+	var hits int32
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error_message": "validation failure"}`)
+	})
+	defer closeServer()
+
+	payloads := make([]*AccountData, 10)
+	for i := range payloads {
+		payloads[i] = populateSingleAccountDataUnitTest()
+		payloads[i].ID = uuid()
+	}
+	// Repeated, as a ready worker used to be able to win over the failure and start another operation.
+	for run := 0; run < 20; run++ {
+		atomic.StoreInt32(&hits, 0)
+		results, err := accountClient.BulkCreate(payloads, &BulkSetting{Concurrency: 1})
+		var bulkError *BulkError
+		if !errors.As(err, &bulkError) || bulkError.Failed != 10 || !isResponseError(err) {
+			t.Fatalf("FAILED: expected a bulk error wrapping the response error, got %v", err)
+		}
+		if hits != 1 {
+			t.Fatalf("FAILED: expected fail-fast to stop after the first failure, got %d requests", hits)
+		}
+		for i, result := range results[1:] {
+			if !errors.Is(result.Err, ErrBulkSkipped) || result.ID != payloads[i+1].ID {
+				t.Fatalf("FAILED: expected operation %d to be skipped under its ID, got %+v", i+1, result)
+			}
+		}
+	}
+	t.Log("SUCCESS: no operation started after the first failure")
+}
+
+func TestBulk_DeleteContinueOnError(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("version") == "1" {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"error_message": "invalid version"}`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer closeServer()
+
+	ids := bulkAccountIds(6)
+	accounts := make([]AccountVersion, len(ids))
+	for i, id := range ids {
+		accounts[i] = AccountVersion{ID: id, Version: i % 2}
+	}
+	results, err := accountClient.BulkDelete(accounts, &BulkSetting{ContinueOnError: true})
+	var bulkError *BulkError
+	if !errors.As(err, &bulkError) || bulkError.Failed != 3 || bulkError.Total != 6 {
+		t.Fatalf("FAILED: expected 3 of 6 deletes to fail, got %v", err)
+	}
+	for i, result := range results {
+		failed := result.Err != nil
+		if failed != (i%2 == 1) || result.ID != ids[i] || result.HttpResponse == nil {
+			t.Errorf("FAILED: unexpected result at index %d: %+v", i, result)
+		}
+	}
+}
+
+func TestBulk_RespectsRateLimit(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{RateLimit: 100, RateBurst: 1},
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+		})
+	defer closeServer()
+
+	start := time.Now()
+	if _, err := accountClient.BulkFetch(bulkAccountIds(6), &BulkSetting{Concurrency: 6}); err != nil {
+		t.Fatalf("FAILED: BulkFetch returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("FAILED: expected 6 requests at 100/s to take at least 50ms, took %v", elapsed)
+	}
+}
+
+func TestBulk_ProgressCallsDoNotOverlap(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	})
+	defer closeServer()
+
+	var active, overlapped int32
+	accountClient.BulkFetch(bulkAccountIds(30), &BulkSetting{
+		Concurrency: 8,
+		OnProgress: func(progress BulkProgress) {
+			if atomic.AddInt32(&active, 1) > 1 {
+				atomic.StoreInt32(&overlapped, 1)
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&active, -1)
+		},
+	})
+	if atomic.LoadInt32(&overlapped) == 1 {
+		t.Errorf("FAILED: expected progress calls not to overlap")
+	}
+}
@@ -0,0 +1,256 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type CircuitState int
+
+const (
+	CIRCUIT_CLOSED CircuitState = iota
+	CIRCUIT_OPEN
+	CIRCUIT_HALF_OPEN
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Configures the circuit breaker kept for every host of the client's base URLs.
+type CircuitBreakerSetting struct {
+	// Ratio of failed requests within the window which opens the circuit.
+	FailureRatio float64
+	// Minimum number of requests within the window before the ratio is evaluated.
+	MinRequests int
+	// Length of the window counting requests while the circuit is closed.
+	Window time.Duration
+	// Time the circuit stays open before trial requests are let through.
+	CoolDown time.Duration
+	// Number of successful trial requests needed to close a half-open circuit.
+	HalfOpenRequests int
+	// Called after the circuit of a host changed its state.
+	OnStateChange func(host string, from CircuitState, to CircuitState)
+}
+
+var CIRCUIT_BREAKER_SETTING_DEFAULT = &CircuitBreakerSetting{
+	FailureRatio:     0.5,
+	MinRequests:      10,
+	Window:           10 * time.Second,
+	CoolDown:         5 * time.Second,
+	HalfOpenRequests: 1,
+}
+
+type circuitOutcome int
+
+const (
+	circuitSuccess circuitOutcome = iota
+	circuitFailure
+	circuitIgnored
+)
+
+type circuitBreaker struct {
+	mutex            sync.Mutex
+	setting          CircuitBreakerSetting
+	host             string
+	state            CircuitState
+	requests         int
+	failures         int
+	windowStart      time.Time
+	openedAt         time.Time
+	halfOpenInFlight int
+	halfOpenSuccess  int
+	generation       uint64
+	changes          [][2]CircuitState
+	now              func() time.Time
+}
+
+type circuitBreakerGroup struct {
+	mutex    sync.Mutex
+	setting  CircuitBreakerSetting
+	breakers map[string]*circuitBreaker
+	// Clock of the breakers created by the group, replaced in tests.
+	now func() time.Time
+}
+
+// Returns the lower case name of the circuit state.
+func (state CircuitState) String() string {
+	switch state {
+	case CIRCUIT_CLOSED:
+		return "closed"
+	case CIRCUIT_OPEN:
+		return "open"
+	case CIRCUIT_HALF_OPEN:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Creates a group of circuit breakers logging state changes, filling unset values from the default setting.
+func newCircuitBreakerGroup(setting *CircuitBreakerSetting, logger Logger) *circuitBreakerGroup {
+	merged := *setting
+	if merged.FailureRatio <= 0 {
+		merged.FailureRatio = CIRCUIT_BREAKER_SETTING_DEFAULT.FailureRatio
+	}
+	if merged.MinRequests <= 0 {
+		merged.MinRequests = CIRCUIT_BREAKER_SETTING_DEFAULT.MinRequests
+	}
+	if merged.Window <= 0 {
+		merged.Window = CIRCUIT_BREAKER_SETTING_DEFAULT.Window
+	}
+	if merged.CoolDown <= 0 {
+		merged.CoolDown = CIRCUIT_BREAKER_SETTING_DEFAULT.CoolDown
+	}
+	if merged.HalfOpenRequests <= 0 {
+		merged.HalfOpenRequests = CIRCUIT_BREAKER_SETTING_DEFAULT.HalfOpenRequests
+	}
+	merged.OnStateChange = func(host string, from CircuitState, to CircuitState) {
+		logger.Log(LOG_LEVEL_WARN, "circuit breaker state changed",
+			Field("host", host), Field("from", from), Field("to", to))
+		if setting.OnStateChange != nil {
+			setting.OnStateChange(host, from, to)
+		}
+	}
+	return &circuitBreakerGroup{
+		setting:  merged,
+		breakers: map[string]*circuitBreaker{},
+		now:      time.Now,
+	}
+}
+
+// Returns the circuit breaker of the host, creating it on first use.
+func (group *circuitBreakerGroup) get(host string) *circuitBreaker {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	breaker, ok := group.breakers[host]
+	if !ok {
+		breaker = &circuitBreaker{
+			setting:     group.setting,
+			host:        host,
+			windowStart: group.now(),
+			now:         group.now,
+		}
+		group.breakers[host] = breaker
+	}
+	return breaker
+}
+
+// Reserves a request on the circuit, failing with ErrCircuitOpen while requests are not allowed.
+// Returns the generation of the circuit which the outcome has to be recorded against.
+func (breaker *circuitBreaker) allow() (uint64, error) {
+	breaker.mutex.Lock()
+	now := breaker.now()
+	var err error
+	switch breaker.state {
+	case CIRCUIT_OPEN:
+		if now.Sub(breaker.openedAt) < breaker.setting.CoolDown {
+			err = ErrCircuitOpen
+			break
+		}
+		breaker.transition(CIRCUIT_HALF_OPEN, now)
+		fallthrough
+	case CIRCUIT_HALF_OPEN:
+		if breaker.halfOpenInFlight+breaker.halfOpenSuccess >= breaker.setting.HalfOpenRequests {
+			err = ErrCircuitOpen
+			break
+		}
+		breaker.halfOpenInFlight++
+	default:
+		if now.Sub(breaker.windowStart) >= breaker.setting.Window {
+			breaker.requests = 0
+			breaker.failures = 0
+			breaker.windowStart = now
+		}
+	}
+	generation := breaker.generation
+	changes := breaker.takeChanges()
+	breaker.mutex.Unlock()
+
+	breaker.notify(changes)
+	return generation, err
+}
+
+// Records the outcome of a request reserved by allow. Outcomes of earlier generations are ignored.
+func (breaker *circuitBreaker) record(generation uint64, outcome circuitOutcome) {
+	breaker.mutex.Lock()
+	now := breaker.now()
+	if generation == breaker.generation {
+		switch breaker.state {
+		case CIRCUIT_HALF_OPEN:
+			breaker.halfOpenInFlight--
+			switch outcome {
+			case circuitFailure:
+				breaker.transition(CIRCUIT_OPEN, now)
+			case circuitSuccess:
+				breaker.halfOpenSuccess++
+				if breaker.halfOpenSuccess >= breaker.setting.HalfOpenRequests {
+					breaker.transition(CIRCUIT_CLOSED, now)
+				}
+			}
+		case CIRCUIT_CLOSED:
+			if outcome != circuitIgnored {
+				breaker.requests++
+				if outcome == circuitFailure {
+					breaker.failures++
+				}
+				if breaker.requests >= breaker.setting.MinRequests &&
+					float64(breaker.failures)/float64(breaker.requests) >= breaker.setting.FailureRatio {
+					breaker.transition(CIRCUIT_OPEN, now)
+				}
+			}
+		}
+	}
+	changes := breaker.takeChanges()
+	breaker.mutex.Unlock()
+
+	breaker.notify(changes)
+}
+
+// Returns the current state of the circuit.
+func (breaker *circuitBreaker) currentState() CircuitState {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	return breaker.state
+}
+
+// Moves the circuit to the new state, resetting its counters. Must be called with the mutex held.
+func (breaker *circuitBreaker) transition(to CircuitState, now time.Time) {
+	breaker.changes = append(breaker.changes, [2]CircuitState{breaker.state, to})
+	breaker.state = to
+	breaker.generation++
+	breaker.requests = 0
+	breaker.failures = 0
+	breaker.windowStart = now
+	breaker.halfOpenInFlight = 0
+	breaker.halfOpenSuccess = 0
+	if to == CIRCUIT_OPEN {
+		breaker.openedAt = now
+	}
+}
+
+// Returns and clears the pending state changes. Must be called with the mutex held.
+func (breaker *circuitBreaker) takeChanges() [][2]CircuitState {
+	changes := breaker.changes
+	breaker.changes = nil
+	return changes
+}
+
+// Calls the state change callback for every change, outside of the mutex.
+func (breaker *circuitBreaker) notify(changes [][2]CircuitState) {
+	for _, change := range changes {
+		breaker.setting.OnStateChange(breaker.host, change[0], change[1])
+	}
+}
+
+// Returns the outcome of a request attempt as seen by the circuit breaker. Requests cancelled
+// by the caller say nothing about the health of the host and are ignored.
+func circuitOutcomeOf(httpResponse *http.Response, err error) circuitOutcome {
+	if errors.Is(err, context.Canceled) {
+		return circuitIgnored
+	}
+	if err != nil || (httpResponse != nil && httpResponse.StatusCode >= 500) {
+		return circuitFailure
+	}
+	return circuitSuccess
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Clock advanced by tests instead of sleeping through cool-downs.
+type manualClock struct {
+	mutex   sync.Mutex
+	current time.Time
+}
+
+func (clock *manualClock) now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.current
+}
+
+func (clock *manualClock) advance(duration time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.current = clock.current.Add(duration)
+}
+
+func TestCircuitBreaker_OpensAndRecovers(t *testing.T) {
+	var healthy int32
+	var hits int32
+	multiplexer := http.NewServeMux()
+	server := httptest.NewServer(multiplexer)
+	defer server.Close()
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE+"/"+SINGLE_ACCOUNT_ID, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error_message": "unavailable"}`)
+			return
+		}
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	})
+
+	var mutex sync.Mutex
+	var changes []string
+	httpClient := NewHttpClient(&ClientSetting{
+		BaseURL: server.URL + UNIT_ACCOUNTS_API_BASE,
+		Timeout: INTEGRATION_TIME_OUT,
+		CircuitBreaker: &CircuitBreakerSetting{
+			FailureRatio: 0.5,
+			MinRequests:  4,
+			CoolDown:     time.Minute,
+			OnStateChange: func(host string, from CircuitState, to CircuitState) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				changes = append(changes, fmt.Sprintf("%s:%v->%v", host, from, to))
+			},
+		},
+	})
+	clock := &manualClock{current: time.Now()}
+	httpClient.breakers.now = clock.now
+	accountClient := NewAccountClient(httpClient)
+	host, _ := url.Parse(server.URL)
+
+	for i := 0; i < 4; i++ {
+		accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	}
+	if state := httpClient.CircuitState(host.Host); state != CIRCUIT_OPEN {
+		t.Fatalf("FAILED: expected circuit to be open, got %v", state)
+	}
+
+	_, _, res, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if !errors.Is(err, ErrCircuitOpen) || res != nil {
+		t.Errorf("FAILED: expected ErrCircuitOpen without response, got %v %v", res, err)
+	}
+	if atomic.LoadInt32(&hits) != 4 {
+		t.Errorf("FAILED: expected open circuit not to reach the server, got %d hits", hits)
+	}
+
+	clock.advance(time.Minute)
+	atomic.StoreInt32(&healthy, 1)
+	if _, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID); err != nil {
+		t.Errorf("FAILED: expected trial request to succeed, got %v", err)
+	}
+	if state := httpClient.CircuitState(host.Host); state != CIRCUIT_CLOSED {
+		t.Errorf("FAILED: expected circuit to be closed after trial, got %v", state)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := []string{
+		host.Host + ":closed->open",
+		host.Host + ":open->half-open",
+		host.Host + ":half-open->closed",
+	}
+	if fmt.Sprint(changes) != fmt.Sprint(expected) {
+		t.Errorf("FAILED: state changes expected %v, got %v", expected, changes)
+	}
+}
+
+func TestCircuitBreaker_HalfOpenFailureReopens(t *testing.T) {
+	group := newCircuitBreakerGroup(&CircuitBreakerSetting{MinRequests: 1, CoolDown: time.Minute}, NewNoopLogger())
+	clock := &manualClock{current: time.Now()}
+	group.now = clock.now
+	breaker := group.get("accountapi:8080")
+
+	generation, _ := breaker.allow()
+	breaker.record(generation, circuitFailure)
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("FAILED: expected open circuit to reject requests, got %v", err)
+	}
+
+	clock.advance(time.Minute)
+	generation, err := breaker.allow()
+	if err != nil || breaker.currentState() != CIRCUIT_HALF_OPEN {
+		t.Fatalf("FAILED: expected trial request while half-open, got %v %v", breaker.currentState(), err)
+	}
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("FAILED: expected only one trial request while half-open, got %v", err)
+	}
+	breaker.record(generation, circuitFailure)
+	if breaker.currentState() != CIRCUIT_OPEN {
+		t.Errorf("FAILED: expected failed trial to reopen the circuit, got %v", breaker.currentState())
+	}
+}
+
+func TestCircuitBreaker_IgnoresCancelledAndClientErrors(t *testing.T) {
+	group := newCircuitBreakerGroup(&CircuitBreakerSetting{MinRequests: 1, FailureRatio: 0.5}, NewNoopLogger())
+	breaker := group.get("accountapi:8080")
+
+	generation, _ := breaker.allow()
+	breaker.record(generation, circuitOutcomeOf(nil, fmt.Errorf("wrapped: %w", context.Canceled)))
+	generation, _ = breaker.allow()
+	breaker.record(generation, circuitOutcomeOf(&http.Response{StatusCode: http.StatusNotFound}, nil))
+	if breaker.currentState() != CIRCUIT_CLOSED {
+		t.Errorf("FAILED: expected circuit to stay closed, got %v", breaker.currentState())
+	}
+}
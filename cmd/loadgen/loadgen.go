@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	client "form3/rest-client"
+)
+
+const (
+	OPERATION_CREATE = "create"
+	OPERATION_FETCH  = "fetch"
+	OPERATION_LIST   = "list"
+	OPERATION_DELETE = "delete"
+)
+
+var OPERATIONS = []string{OPERATION_CREATE, OPERATION_FETCH, OPERATION_LIST, OPERATION_DELETE}
+
+// Prefix of the organisation ID of every account created, so accounts left behind by an
+// interrupted run can be swept with client.SweepSetting.
+const LOADGEN_ORGANISATION_ID_PREFIX = "10ad0000-"
+
+// Operation mix used when none is given.
+const MIX_DEFAULT = "create=2,fetch=5,list=1,delete=2"
+
+// Relative weights of the operations sent, by operation name.
+type Mix map[string]int
+
+// Configures a load run.
+type Config struct {
+	// Requests started per second.
+	Rate     float64
+	Duration time.Duration
+	Mix      Mix
+	// Maximum number of requests in flight. Requests due while all are in use are dropped.
+	Concurrency int
+	// Number of accounts created before the run, so fetches and deletes have accounts to work on.
+	Prefill  int
+	PageSize int
+	Seed     int64
+	// Deletes the accounts still known at the end of the run.
+	Cleanup bool
+}
+
+// Result of a load run.
+type Report struct {
+	Label           string                      `json:"label,omitempty"`
+	Target          string                      `json:"target"`
+	Mix             Mix                         `json:"mix"`
+	TargetRate      float64                     `json:"target_rate"`
+	AchievedRate    float64                     `json:"achieved_rate"`
+	DurationSeconds float64                     `json:"duration_seconds"`
+	Concurrency     int                         `json:"concurrency"`
+	Requests        int                         `json:"requests"`
+	Errors          int                         `json:"errors"`
+	Dropped         int                         `json:"dropped"`
+	Operations      map[string]*OperationReport `json:"operations"`
+	ErrorKinds      map[string]int              `json:"error_kinds"`
+	Allocations     AllocationReport            `json:"allocations"`
+	CleanupFailed   int                         `json:"cleanup_failed"`
+	GoVersion       string                      `json:"go_version"`
+}
+
+// Requests, errors by kind and latencies of one operation.
+type OperationReport struct {
+	Requests int            `json:"requests"`
+	Errors   map[string]int `json:"errors,omitempty"`
+	Latency  LatencyReport  `json:"latency_ms"`
+}
+
+// Latency distribution in milliseconds.
+type LatencyReport struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p999"`
+	Max  float64 `json:"max"`
+}
+
+// Heap allocations and garbage collections during the run, including those of the in-process fake
+// when it is the target.
+type AllocationReport struct {
+	Bytes             uint64  `json:"bytes"`
+	Objects           uint64  `json:"objects"`
+	BytesPerRequest   float64 `json:"bytes_per_request"`
+	ObjectsPerRequest float64 `json:"objects_per_request"`
+	GCCycles          uint32  `json:"gc_cycles"`
+	GCPauseMs         float64 `json:"gc_pause_ms"`
+}
+
+type accountRef struct {
+	id      string
+	version int
+}
+
+type loadRunner struct {
+	config        Config
+	accountClient *client.AccountClient
+	mutex         sync.Mutex
+	random        *rand.Rand
+	generator     *client.AccountGenerator
+	accounts      []accountRef
+	latencies     map[string][]time.Duration
+	errors        map[string]map[string]int
+}
+
+// Parses a mix such as "create=2,fetch=5", where operations left out are not sent.
+func ParseMix(value string) (Mix, error) {
+	mix := Mix{}
+	total := 0
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		operation, rawWeight := part, "1"
+		if index := strings.Index(part, "="); index >= 0 {
+			operation, rawWeight = strings.TrimSpace(part[:index]), strings.TrimSpace(part[index+1:])
+		}
+		if !isOperation(operation) {
+			return nil, fmt.Errorf("unknown operation %q in mix, expected one of %s", operation, strings.Join(OPERATIONS, ", "))
+		}
+		weight, err := strconv.Atoi(rawWeight)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q for operation %s in mix", rawWeight, operation)
+		}
+		mix[operation] += weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("mix %q has no operation with a positive weight", value)
+	}
+	return mix, nil
+}
+
+// Returns a random operation by weight.
+func (mix Mix) pick(random *rand.Rand) string {
+	total := 0
+	for _, operation := range OPERATIONS {
+		total += mix[operation]
+	}
+	choice := random.Intn(total)
+	for _, operation := range OPERATIONS {
+		if choice < mix[operation] {
+			return operation
+		}
+		choice -= mix[operation]
+	}
+	return OPERATION_CREATE
+}
+
+func isOperation(value string) bool {
+	for _, operation := range OPERATIONS {
+		if operation == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Sends the configured mix of operations through the account client at the configured rate until
+// the duration has passed or the context is done, and reports on the requests sent.
+// Requests are started on schedule whether or not earlier ones have completed, up to the
+// concurrency limit, so a slow API shows up as latency and dropped requests rather than a lower rate.
+func Run(ctx context.Context, accountClient *client.AccountClient, config Config) (*Report, error) {
+	if config.Rate <= 0 || config.Concurrency <= 0 || config.Duration <= 0 {
+		return nil, errors.New("rate, concurrency and duration must be positive")
+	}
+	runner := &loadRunner{
+		config:        config,
+		accountClient: accountClient,
+		random:        rand.New(rand.NewSource(config.Seed)),
+		generator:     client.NewAccountGenerator(config.Seed),
+		latencies:     map[string][]time.Duration{},
+		errors:        map[string]map[string]int{},
+	}
+	if err := runner.prefill(ctx); err != nil {
+		runner.cleanup()
+		return nil, err
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	interval := time.Duration(float64(time.Second) / config.Rate)
+	slots := make(chan struct{}, config.Concurrency)
+	var group sync.WaitGroup
+	dropped := 0
+	start := time.Now()
+	for i := 0; ; i++ {
+		due := start.Add(time.Duration(i) * interval)
+		if due.Sub(start) >= config.Duration || ctx.Err() != nil {
+			break
+		}
+		if wait := time.Until(due); wait > 0 {
+			time.Sleep(wait)
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			dropped++
+			continue
+		}
+		runner.mutex.Lock()
+		operation := config.Mix.pick(runner.random)
+		runner.mutex.Unlock()
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			defer func() { <-slots }()
+			runner.execute(ctx, operation)
+		}()
+	}
+	group.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	report := runner.report(elapsed)
+	report.Dropped = dropped
+	report.Allocations = allocationReport(&before, &after, report.Requests)
+	report.CleanupFailed = runner.cleanup()
+	return report, nil
+}
+
+// Deletes the accounts still known when cleanup is configured, even when the run was interrupted.
+// Accounts which are already gone count as deleted. Returns the number of accounts not deleted.
+func (runner *loadRunner) cleanup() int {
+	if !runner.config.Cleanup || len(runner.accounts) == 0 {
+		return 0
+	}
+	accounts := make([]client.AccountVersion, len(runner.accounts))
+	for i, account := range runner.accounts {
+		accounts[i] = client.AccountVersion{ID: account.id, Version: account.version}
+	}
+	results, _ := runner.accountClient.BulkDelete(accounts, &client.BulkSetting{
+		Concurrency:     runner.config.Concurrency,
+		ContinueOnError: true,
+	})
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil && (result.HttpResponse == nil || result.HttpResponse.StatusCode != http.StatusNotFound) {
+			failed++
+		}
+	}
+	runner.accounts = nil
+	return failed
+}
+
+// Creates the configured number of accounts one at a time.
+func (runner *loadRunner) prefill(ctx context.Context) error {
+	for i := 0; i < runner.config.Prefill; i++ {
+		account, _, _, err := runner.accountClient.CreateAccountWithContext(ctx, runner.account())
+		if err != nil {
+			return fmt.Errorf("creating prefill account %d: %w", i+1, err)
+		}
+		runner.accounts = append(runner.accounts, refOf(account))
+	}
+	return nil
+}
+
+// Generates an account payload whose organisation ID starts with LOADGEN_ORGANISATION_ID_PREFIX.
+func (runner *loadRunner) account() *client.AccountData {
+	account := runner.generator.Account()
+	account.OrganisationID = LOADGEN_ORGANISATION_ID_PREFIX + runner.generator.UUID()[len(LOADGEN_ORGANISATION_ID_PREFIX):]
+	return account
+}
+
+// Sends one operation and records its latency and outcome. Fetches and deletes fall back to
+// creating an account when no account is known.
+func (runner *loadRunner) execute(ctx context.Context, operation string) {
+	runner.mutex.Lock()
+	var account accountRef
+	if operation == OPERATION_FETCH || operation == OPERATION_DELETE {
+		if len(runner.accounts) == 0 {
+			operation = OPERATION_CREATE
+		} else {
+			index := runner.random.Intn(len(runner.accounts))
+			account = runner.accounts[index]
+			if operation == OPERATION_DELETE {
+				last := len(runner.accounts) - 1
+				runner.accounts[index] = runner.accounts[last]
+				runner.accounts = runner.accounts[:last]
+			}
+		}
+	}
+	var payload *client.AccountData
+	if operation == OPERATION_CREATE {
+		payload = runner.account()
+	}
+	runner.mutex.Unlock()
+
+	var created *client.AccountData
+	var httpResponse *http.Response
+	var err error
+	start := time.Now()
+	switch operation {
+	case OPERATION_CREATE:
+		created, _, httpResponse, err = runner.accountClient.CreateAccountWithContext(ctx, payload)
+	case OPERATION_FETCH:
+		_, _, httpResponse, err = runner.accountClient.FetchByIdWithContext(ctx, account.id)
+	case OPERATION_LIST:
+		_, _, httpResponse, err = runner.accountClient.ListAccountWithContext(ctx, &client.AccountParams{Number: "0", Size: runner.config.PageSize})
+	case OPERATION_DELETE:
+		httpResponse, err = runner.accountClient.DeleteAccountWithContext(ctx, account.id, account.version)
+	}
+	latency := time.Since(start)
+
+	runner.mutex.Lock()
+	defer runner.mutex.Unlock()
+	runner.latencies[operation] = append(runner.latencies[operation], latency)
+	if err != nil {
+		if runner.errors[operation] == nil {
+			runner.errors[operation] = map[string]int{}
+		}
+		status := 0
+		if httpResponse != nil {
+			status = httpResponse.StatusCode
+		}
+		runner.errors[operation][client.ClassifyError(status, err)]++
+		if operation == OPERATION_DELETE && (httpResponse == nil || httpResponse.StatusCode != http.StatusNotFound) {
+			// The account may still exist, so it stays available to later operations.
+			runner.accounts = append(runner.accounts, account)
+		}
+		return
+	}
+	if created != nil {
+		runner.accounts = append(runner.accounts, refOf(created))
+	}
+}
+
+// Summarises the recorded latencies and errors.
+func (runner *loadRunner) report(elapsed time.Duration) *Report {
+	runner.mutex.Lock()
+	defer runner.mutex.Unlock()
+	report := &Report{
+		Mix:             runner.config.Mix,
+		TargetRate:      runner.config.Rate,
+		DurationSeconds: elapsed.Seconds(),
+		Concurrency:     runner.config.Concurrency,
+		Operations:      map[string]*OperationReport{},
+		ErrorKinds:      map[string]int{},
+		GoVersion:       runtime.Version(),
+	}
+	for operation, latencies := range runner.latencies {
+		operationReport := &OperationReport{
+			Requests: len(latencies),
+			Errors:   runner.errors[operation],
+			Latency:  latencyReport(latencies),
+		}
+		report.Operations[operation] = operationReport
+		report.Requests += operationReport.Requests
+		for kind, count := range operationReport.Errors {
+			report.ErrorKinds[kind] += count
+			report.Errors += count
+		}
+	}
+	if elapsed > 0 {
+		report.AchievedRate = float64(report.Requests) / elapsed.Seconds()
+	}
+	return report
+}
+
+// Returns the latency distribution, sorting the latencies in place.
+func latencyReport(latencies []time.Duration) LatencyReport {
+	if len(latencies) == 0 {
+		return LatencyReport{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	return LatencyReport{
+		Min:  milliseconds(latencies[0]),
+		Mean: milliseconds(total / time.Duration(len(latencies))),
+		P50:  milliseconds(percentile(latencies, 0.5)),
+		P90:  milliseconds(percentile(latencies, 0.9)),
+		P95:  milliseconds(percentile(latencies, 0.95)),
+		P99:  milliseconds(percentile(latencies, 0.99)),
+		P999: milliseconds(percentile(latencies, 0.999)),
+		Max:  milliseconds(latencies[len(latencies)-1]),
+	}
+}
+
+// Returns the latency below which the share of the sorted latencies falls.
+func percentile(sorted []time.Duration, share float64) time.Duration {
+	index := int(share * float64(len(sorted)))
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+// Returns the allocations made between the two memory statistics.
+func allocationReport(before *runtime.MemStats, after *runtime.MemStats, requests int) AllocationReport {
+	report := AllocationReport{
+		Bytes:     after.TotalAlloc - before.TotalAlloc,
+		Objects:   after.Mallocs - before.Mallocs,
+		GCCycles:  after.NumGC - before.NumGC,
+		GCPauseMs: milliseconds(time.Duration(after.PauseTotalNs - before.PauseTotalNs)),
+	}
+	if requests > 0 {
+		report.BytesPerRequest = float64(report.Bytes) / float64(requests)
+		report.ObjectsPerRequest = float64(report.Objects) / float64(requests)
+	}
+	return report
+}
+
+func refOf(account *client.AccountData) accountRef {
+	ref := accountRef{id: account.ID}
+	if account.Version != nil {
+		ref.version = int(*account.Version)
+	}
+	return ref
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	client "form3/rest-client"
+	"form3/rest-client/accountapitest"
+)
+
+func TestLoadgen_ParseMix(t *testing.T) {
+	mix, err := ParseMix("create=2, fetch=5,list,delete=0")
+	expected := Mix{OPERATION_CREATE: 2, OPERATION_FETCH: 5, OPERATION_LIST: 1, OPERATION_DELETE: 0}
+	if err != nil || !reflect.DeepEqual(mix, expected) {
+		t.Errorf("FAILED: expected mix %v, got %v %v", expected, mix, err)
+	}
+	for _, invalid := range []string{"", "update=1", "fetch=-1", "fetch=x", "create=0"} {
+		if _, err := ParseMix(invalid); err == nil {
+			t.Errorf("FAILED: expected mix %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestLoadgen_LatencyReport(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	report := latencyReport(latencies)
+	if report.Min != 1 || report.P50 != 51 || report.P99 != 100 || report.Max != 100 || report.Mean != 50.5 {
+		t.Errorf("FAILED: unexpected latency report %+v", report)
+	}
+}
+
+func TestLoadgen_RunAgainstFake(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+	httpClient := client.NewHttpClient(&client.ClientSetting{BaseURL: server.BaseURL, Timeout: 5000})
+
+	report, err := Run(context.Background(), client.NewAccountClient(httpClient), Config{
+		Rate:        200,
+		Duration:    250 * time.Millisecond,
+		Mix:         Mix{OPERATION_CREATE: 1, OPERATION_FETCH: 1, OPERATION_LIST: 1, OPERATION_DELETE: 1},
+		Concurrency: 16,
+		Prefill:     5,
+		PageSize:    10,
+		Seed:        1,
+	})
+	if err != nil {
+		t.Fatalf("FAILED: Run returned error: %v", err)
+	}
+	if report.Requests+report.Dropped != 50 {
+		t.Errorf("FAILED: expected 50 scheduled requests, got %d sent and %d dropped", report.Requests, report.Dropped)
+	}
+	// A fetch may race with the delete of the same account, so only its not found errors are expected.
+	if report.Errors != report.Operations[OPERATION_FETCH].Errors[client.ERROR_CLASS_CLIENT_ERROR] {
+		t.Errorf("FAILED: expected no errors against the fake, got %v", report.ErrorKinds)
+	}
+	for _, operation := range OPERATIONS {
+		if report.Operations[operation] == nil || report.Operations[operation].Latency.Max <= 0 {
+			t.Errorf("FAILED: expected latencies for %s, got %+v", operation, report.Operations[operation])
+		}
+	}
+	if report.Allocations.Objects == 0 {
+		t.Errorf("FAILED: expected allocations to be measured, got %+v", report.Allocations)
+	}
+	remaining := server.Handler.Accounts()
+	if len(remaining) != 5+report.Operations[OPERATION_CREATE].Requests-report.Operations[OPERATION_DELETE].Requests {
+		t.Errorf("FAILED: expected deletes to remove created accounts, %d accounts remain", len(remaining))
+	} else {
+		t.Logf("SUCCESS: sent %d requests at %.0f/s", report.Requests, report.AchievedRate)
+	}
+	for _, account := range remaining {
+		if !strings.HasPrefix(account.OrganisationID, LOADGEN_ORGANISATION_ID_PREFIX) {
+			t.Errorf("FAILED: expected organisation ID with prefix %s, got %s", LOADGEN_ORGANISATION_ID_PREFIX, account.OrganisationID)
+			break
+		}
+	}
+}
+
+func TestLoadgen_CleanupDeletesAccounts(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+	httpClient := client.NewHttpClient(&client.ClientSetting{BaseURL: server.BaseURL, Timeout: 5000})
+
+	report, err := Run(context.Background(), client.NewAccountClient(httpClient), Config{
+		Rate:        200,
+		Duration:    100 * time.Millisecond,
+		Mix:         Mix{OPERATION_CREATE: 1},
+		Concurrency: 4,
+		Prefill:     5,
+		Seed:        1,
+		Cleanup:     true,
+	})
+	if err != nil {
+		t.Fatalf("FAILED: Run returned error: %v", err)
+	}
+	if remaining := len(server.Handler.Accounts()); remaining != 0 || report.CleanupFailed != 0 {
+		t.Errorf("FAILED: expected cleanup to delete every account, %d remain and %d failed", remaining, report.CleanupFailed)
+	} else {
+		t.Logf("SUCCESS: cleanup deleted %d accounts", 5+report.Operations[OPERATION_CREATE].Requests)
+	}
+}
@@ -0,0 +1,97 @@
+// Command loadgen drives a configurable mix of create, fetch, list and delete requests against the
+// account API at a target rate, and reports latency percentiles, errors and allocations as JSON so
+// runs can be compared across client versions. The accounts created are deleted at the end unless
+// -cleanup=false, and their organisation IDs start with LOADGEN_ORGANISATION_ID_PREFIX.
+//
+// Without -base-url the requests are sent to an in-process fake of the account API:
+//
+//	go run ./cmd/loadgen -rate 1000 -duration 30s -mix create=1,fetch=8,list=1 -output report.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	client "form3/rest-client"
+	"form3/rest-client/accountapitest"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "loadgen:", err)
+		os.Exit(1)
+	}
+}
+
+// Runs the load described by the flags and writes its report, returning once every deferred
+// cleanup has run.
+func run() error {
+	baseURL := flag.String("base-url", "", "base URL of the accounts resource, the in-process fake when empty")
+	rate := flag.Float64("rate", 1000, "requests started per second")
+	duration := flag.Duration("duration", 10*time.Second, "how long requests are started for")
+	mix := flag.String("mix", MIX_DEFAULT, "relative weights of the create, fetch, list and delete operations")
+	concurrency := flag.Int("concurrency", 256, "maximum requests in flight, requests due beyond it are dropped")
+	prefill := flag.Int("prefill", 100, "accounts created before the run for fetches and deletes")
+	pageSize := flag.Int("page-size", 100, "page size of list requests")
+	timeout := flag.Int("timeout", 5000, "request timeout in milliseconds")
+	seed := flag.Int64("seed", 1, "seed of the operation order and generated accounts")
+	cleanup := flag.Bool("cleanup", true, "delete the accounts still known at the end of the run")
+	label := flag.String("label", "", "label stored in the report, such as the client version")
+	output := flag.String("output", "", "file the JSON report is written to, stdout when empty")
+	flag.Parse()
+
+	parsedMix, err := ParseMix(*mix)
+	if err != nil {
+		return err
+	}
+	target := *baseURL
+	if target == "" {
+		server := accountapitest.NewServer()
+		defer server.Close()
+		*baseURL = server.BaseURL
+		target = "fake"
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = *concurrency
+	httpClient := client.NewHttpClient(&client.ClientSetting{
+		BaseURL:   *baseURL,
+		Timeout:   *timeout,
+		Transport: transport,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := Run(ctx, client.NewAccountClient(httpClient), Config{
+		Rate:        *rate,
+		Duration:    *duration,
+		Mix:         parsedMix,
+		Concurrency: *concurrency,
+		Prefill:     *prefill,
+		PageSize:    *pageSize,
+		Seed:        *seed,
+		Cleanup:     *cleanup,
+	})
+	if err != nil {
+		return err
+	}
+	report.Label = *label
+	report.Target = target
+
+	encoded, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	encoded = append(encoded, '\n')
+	if *output == "" {
+		_, err = os.Stdout.Write(encoded)
+		return err
+	}
+	return os.WriteFile(*output, encoded, 0644)
+}
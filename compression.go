@@ -0,0 +1,48 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	ACCEPT_ENCODING_HEADER  = "Accept-Encoding"
+	CONTENT_ENCODING_HEADER = "Content-Encoding"
+	GZIP_ENCODING           = "gzip"
+)
+
+// Returns a reader of the decompressed response body. Decompression is done here rather than
+// by the transport, so it also happens with transports that disable Go's automatic handling.
+func decompressedBody(httpResponse *http.Response) (io.ReadCloser, error) {
+	if !strings.EqualFold(httpResponse.Header.Get(CONTENT_ENCODING_HEADER), GZIP_ENCODING) {
+		return httpResponse.Body, nil
+	}
+	reader, err := gzip.NewReader(httpResponse.Body)
+	if err == io.EOF {
+		return http.NoBody, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	httpResponse.Header.Del(CONTENT_ENCODING_HEADER)
+	httpResponse.Header.Del("Content-Length")
+	httpResponse.ContentLength = -1
+	httpResponse.Uncompressed = true
+	return reader, nil
+}
+
+// Compresses a request body with gzip.
+func compressBody(body []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
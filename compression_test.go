@@ -0,0 +1,117 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func gzipHandler(body string, acceptEncoding *string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if acceptEncoding != nil {
+			*acceptEncoding = r.Header.Get("Accept-Encoding")
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		writer.Write([]byte(body))
+		writer.Close()
+	}
+}
+
+func TestCompression_GzipResponse(t *testing.T) {
+	var acceptEncoding string
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil,
+		gzipHandler(SINGLE_ACCOUNT_MOCK_RESPONSE, &acceptEncoding))
+	defer closeServer()
+
+	account, _, res, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if err != nil || account.ID != SINGLE_ACCOUNT_ID {
+		t.Fatalf("FAILED: expected a gzip response to be decoded, got %v %v", account, err)
+	}
+	if acceptEncoding != "gzip" {
+		t.Errorf("FAILED: expected Accept-Encoding gzip, got %q", acceptEncoding)
+	}
+	if res.Header.Get("Content-Encoding") != "" || !res.Uncompressed {
+		t.Errorf("FAILED: expected the response to be marked uncompressed, got %v", res.Header)
+	}
+	t.Log("SUCCESS: gzip response was decompressed")
+}
+
+func TestCompression_CustomTransportWithoutDecompression(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{
+		Transport: &http.Transport{DisableCompression: true},
+	}, gzipHandler(SINGLE_ACCOUNT_MOCK_RESPONSE, nil))
+	defer closeServer()
+
+	account, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if err != nil || account.ID != SINGLE_ACCOUNT_ID {
+		t.Errorf("FAILED: expected decompression with a custom transport, got %v %v", account, err)
+	}
+}
+
+func TestCompression_MaxResponseSize(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{MaxResponseSize: 256},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(SINGLE_ACCOUNT_MOCK_RESPONSE))
+		})
+	defer closeServer()
+
+	_, _, res, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("FAILED: expected ErrResponseTooLarge, got %v %v", res, err)
+	}
+}
+
+func TestCompression_MaxResponseSizeAppliesAfterDecompression(t *testing.T) {
+	huge := `{"data": {"id": "` + strings.Repeat("a", 1<<20) + `"}}`
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{MaxResponseSize: 64 << 10},
+		gzipHandler(huge, nil))
+	defer closeServer()
+
+	if _, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("FAILED: expected a highly compressed body to be capped, got %v", err)
+	}
+}
+
+func TestCompression_LargeRequestBodyCompressed(t *testing.T) {
+	var contentEncoding string
+	var received ResponseBody
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{CompressRequestsOver: 100},
+		func(w http.ResponseWriter, r *http.Request) {
+			contentEncoding = r.Header.Get("Content-Encoding")
+			reader, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("FAILED: expected a gzip request body, got %v", err)
+				return
+			}
+			body, _ := ioutil.ReadAll(reader)
+			json.Unmarshal(body, &received)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(SINGLE_ACCOUNT_MOCK_RESPONSE))
+		})
+	defer closeServer()
+
+	if _, _, _, err := accountClient.CreateAccount(populateSingleAccountDataUnitTest()); err != nil {
+		t.Fatalf("FAILED: CreateAccount returned error: %v", err)
+	}
+	if contentEncoding != "gzip" || received.Data == nil {
+		t.Errorf("FAILED: expected a gzip compressed payload, got %q %v", contentEncoding, received)
+	}
+}
+
+func TestCompression_SmallRequestBodyNotCompressed(t *testing.T) {
+	httpClient := NewHttpClient(&ClientSetting{CompressRequestsOver: 1 << 20})
+	request, err := httpClient.newHttpRequest("POST", "http://localhost", populateSingleAccountDataUnitTest())
+	if err != nil {
+		t.Fatalf("FAILED: newHttpRequest returned error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(request.Body)
+	if request.Header.Get("Content-Encoding") != "" || !bytes.HasPrefix(body, []byte(`{"data"`)) {
+		t.Errorf("FAILED: expected a plain JSON body, got %q", body)
+	}
+}
@@ -0,0 +1,235 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type EndpointSelection int
+
+const (
+	// Sends requests to the first healthy base URL in the configured order.
+	ENDPOINT_SELECTION_PRIORITY EndpointSelection = iota
+	// Spreads requests over the healthy base URLs in turn.
+	ENDPOINT_SELECTION_ROUND_ROBIN
+)
+
+const (
+	ENDPOINT_EJECT_AFTER_DEFAULT    = 3
+	ENDPOINT_PROBE_INTERVAL_DEFAULT = 10 * time.Second
+)
+
+type endpoint struct {
+	baseURL   string
+	failures  int
+	ejected   bool
+	probing   bool
+	lastProbe time.Time
+}
+
+// Tracks the health of several base URLs serving the same API. Endpoints are ejected after
+// consecutive failures and re-admitted once a probe request succeeds.
+type endpointPool struct {
+	mutex         sync.Mutex
+	endpoints     []*endpoint
+	selection     EndpointSelection
+	ejectAfter    int
+	probeInterval time.Duration
+	next          int
+	probe         func(baseURL string) bool
+	logger        Logger
+}
+
+// Creates an endpoint pool for the base URLs, probing ejected endpoints with the probe function.
+func newEndpointPool(baseURLs []string, setting *ClientSetting, probe func(baseURL string) bool, logger Logger) *endpointPool {
+	pool := &endpointPool{
+		selection:     setting.EndpointSelection,
+		ejectAfter:    setting.EjectAfter,
+		probeInterval: setting.ProbeInterval,
+		probe:         probe,
+		logger:        logger,
+	}
+	if pool.ejectAfter <= 0 {
+		pool.ejectAfter = ENDPOINT_EJECT_AFTER_DEFAULT
+	}
+	if pool.probeInterval <= 0 {
+		pool.probeInterval = ENDPOINT_PROBE_INTERVAL_DEFAULT
+	}
+	for _, baseURL := range baseURLs {
+		pool.endpoints = append(pool.endpoints, &endpoint{baseURL: strings.TrimSuffix(baseURL, "/")})
+	}
+	return pool
+}
+
+// Returns the endpoints to try for a request in order: healthy endpoints by the selection
+// strategy, followed by ejected endpoints as a last resort. Probes of ejected endpoints are
+// started when their probe interval has passed.
+func (pool *endpointPool) candidates() []*endpoint {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	var healthy, ejected []*endpoint
+	now := time.Now()
+	for _, endpoint := range pool.endpoints {
+		if !endpoint.ejected {
+			healthy = append(healthy, endpoint)
+			continue
+		}
+		ejected = append(ejected, endpoint)
+		if !endpoint.probing && now.Sub(endpoint.lastProbe) >= pool.probeInterval {
+			endpoint.probing = true
+			endpoint.lastProbe = now
+			go pool.runProbe(endpoint)
+		}
+	}
+
+	if pool.selection == ENDPOINT_SELECTION_ROUND_ROBIN && len(healthy) > 1 {
+		start := pool.next % len(healthy)
+		pool.next++
+		healthy = append(healthy[start:], healthy[:start]...)
+	}
+	return append(healthy, ejected...)
+}
+
+// Records the outcome of a request sent to the endpoint.
+func (pool *endpointPool) record(endpoint *endpoint, success bool) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if success {
+		endpoint.failures = 0
+		if endpoint.ejected {
+			pool.readmit(endpoint)
+		}
+		return
+	}
+	endpoint.failures++
+	if !endpoint.ejected && endpoint.failures >= pool.ejectAfter {
+		endpoint.ejected = true
+		endpoint.lastProbe = time.Now()
+		pool.logger.Log(LOG_LEVEL_WARN, "endpoint ejected",
+			Field("base_url", endpoint.baseURL), Field("failures", endpoint.failures))
+	}
+}
+
+// Returns the base URLs which are currently not ejected.
+func (pool *endpointPool) healthy() []string {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	var baseURLs []string
+	for _, endpoint := range pool.endpoints {
+		if !endpoint.ejected {
+			baseURLs = append(baseURLs, endpoint.baseURL)
+		}
+	}
+	return baseURLs
+}
+
+// Probes an ejected endpoint, re-admitting it when the probe succeeds.
+func (pool *endpointPool) runProbe(endpoint *endpoint) {
+	success := pool.probe(endpoint.baseURL)
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	endpoint.probing = false
+	if success && endpoint.ejected {
+		pool.readmit(endpoint)
+	}
+}
+
+// Marks the endpoint healthy again. Must be called with the mutex held.
+func (pool *endpointPool) readmit(endpoint *endpoint) {
+	endpoint.ejected = false
+	endpoint.failures = 0
+	pool.logger.Log(LOG_LEVEL_INFO, "endpoint readmitted", Field("base_url", endpoint.baseURL))
+}
+
+// Executes the http request against the healthy endpoints, failing idempotent requests over
+// to the next endpoint when a request fails because of the endpoint.
+// Returns http response and response body.
+func (httpClient *HttpClient) failover(httpRequest *http.Request, route string, attempts *int32) (*http.Response, []byte, error) {
+	primary := strings.TrimSuffix(httpClient.BaseURL, "/")
+	requestURL := httpRequest.URL.String()
+	if primary == "" || !strings.HasPrefix(requestURL, primary) {
+		return httpClient.executeOnce(httpRequest, route, attempts)
+	}
+
+	var httpResponse *http.Response
+	var responseBytes []byte
+	var err error
+	for i, endpoint := range httpClient.endpoints.candidates() {
+		endpointURL, parseErr := url.Parse(endpoint.baseURL + strings.TrimPrefix(requestURL, primary))
+		if parseErr != nil {
+			return nil, nil, parseErr
+		}
+		endpointRequest := httpRequest.Clone(httpRequest.Context())
+		endpointRequest.URL = endpointURL
+		endpointRequest.Host = ""
+		if i > 0 {
+			httpClient.logger.Log(LOG_LEVEL_WARN, "request failing over",
+				Field("method", httpRequest.Method), Field("base_url", endpoint.baseURL),
+				Field("request_id", httpRequest.Header.Get(REQUEST_ID_HEADER)), Field("error", err))
+		}
+
+		httpResponse, responseBytes, err = httpClient.executeOnce(endpointRequest, route, attempts)
+		outcome := circuitOutcomeOf(httpResponse, err)
+		if !errors.Is(err, ErrCircuitOpen) && outcome != circuitIgnored {
+			httpClient.endpoints.record(endpoint, outcome == circuitSuccess)
+		}
+		if outcome != circuitFailure || !isIdempotent(httpRequest.Method) || httpRequest.Context().Err() != nil {
+			break
+		}
+		if httpResponse != nil && httpResponse.StatusCode < 300 && bodyConsumerFromContext(httpRequest.Context()) != nil {
+			// Part of the streamed body may already have been consumed.
+			break
+		}
+	}
+	return httpResponse, responseBytes, err
+}
+
+// Sends a GET request to the base URL, reporting whether the endpoint answered without a server error.
+func (httpClient *HttpClient) probeEndpoint(baseURL string) bool {
+	ctx := context.Background()
+	if httpClient.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, httpClient.timeout)
+		defer cancel()
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL, nil)
+	if err != nil {
+		return false
+	}
+	httpResponse, err := httpClient.client.Do(httpRequest)
+	if err != nil {
+		return false
+	}
+	httpResponse.Body.Close()
+	return httpResponse.StatusCode < 500
+}
+
+// Returns the base URLs which are currently considered healthy.
+func (httpClient *HttpClient) HealthyBaseURLs() []string {
+	if httpClient.endpoints == nil {
+		return []string{httpClient.BaseURL}
+	}
+	return httpClient.endpoints.healthy()
+}
+
+// Returns whether repeating a request with the http method has no additional effect.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Returns the number of the next attempt of a request, counted over hedges and failovers.
+func nextAttempt(attempts *int32) int {
+	return int(atomic.AddInt32(attempts, 1))
+}
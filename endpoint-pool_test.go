@@ -0,0 +1,211 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func prepareTestEndpointServer(handler http.HandlerFunc) *httptest.Server {
+	multiplexer := http.NewServeMux()
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE+"/"+SINGLE_ACCOUNT_ID, handler)
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE, handler)
+	return httptest.NewServer(multiplexer)
+}
+
+func prepareTestFailoverAccountClient(setting *ClientSetting, servers ...*httptest.Server) *AccountClient {
+	for _, server := range servers {
+		setting.BaseURLs = append(setting.BaseURLs, server.URL+UNIT_ACCOUNTS_API_BASE)
+	}
+	setting.Timeout = INTEGRATION_TIME_OUT
+	return NewAccountClient(NewHttpClient(setting))
+}
+
+func unavailableHandler(hits *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error_message": "unavailable"}`)
+	}
+}
+
+func accountHandler(hits *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	}
+}
+
+func TestEndpointPool_GetFailsOver(t *testing.T) {
+	var primaryHits, secondaryHits int32
+	primary := prepareTestEndpointServer(unavailableHandler(&primaryHits))
+	defer primary.Close()
+	secondary := prepareTestEndpointServer(accountHandler(&secondaryHits))
+	defer secondary.Close()
+	collector := NewPrometheusCollector(nil)
+	accountClient := prepareTestFailoverAccountClient(&ClientSetting{Metrics: collector}, primary, secondary)
+
+	account, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if err != nil || account.ID != SINGLE_ACCOUNT_ID {
+		t.Fatalf("FAILED: expected FetchById to fail over, got %v %v", account, err)
+	}
+	if primaryHits != 1 || secondaryHits != 1 {
+		t.Errorf("FAILED: expected one request per endpoint, got %d and %d", primaryHits, secondaryHits)
+	}
+	if collector.retries[metricKey{method: "GET", route: "/v1/organisation/accounts/{id}"}] != 1 {
+		t.Errorf("FAILED: expected the failover to be counted as retry, got %v", collector.retries)
+	}
+	t.Log("SUCCESS: GET failed over to the second endpoint")
+}
+
+func TestEndpointPool_EveryFailoverCountedAsRetry(t *testing.T) {
+	var hits int32
+	servers := []*httptest.Server{
+		prepareTestEndpointServer(unavailableHandler(&hits)),
+		prepareTestEndpointServer(unavailableHandler(&hits)),
+		prepareTestEndpointServer(unavailableHandler(&hits)),
+	}
+	for _, server := range servers {
+		defer server.Close()
+	}
+	collector := NewPrometheusCollector(nil)
+	accountClient := prepareTestFailoverAccountClient(&ClientSetting{Metrics: collector}, servers...)
+
+	if _, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID); err == nil {
+		t.Fatalf("FAILED: expected FetchById to fail when every endpoint is unavailable")
+	}
+	key := metricKey{method: "GET", route: "/v1/organisation/accounts/{id}"}
+	if hits != 3 || collector.retries[key] != 2 {
+		t.Errorf("FAILED: expected 3 attempts counted as 2 retries, got %d attempts and %v", hits, collector.retries)
+	}
+}
+
+func TestEndpointPool_UnreachableEndpointFailsOver(t *testing.T) {
+	var secondaryHits int32
+	primary := prepareTestEndpointServer(accountHandler(new(int32)))
+	primary.Close()
+	secondary := prepareTestEndpointServer(accountHandler(&secondaryHits))
+	defer secondary.Close()
+	accountClient := prepareTestFailoverAccountClient(&ClientSetting{}, primary, secondary)
+
+	if _, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID); err != nil {
+		t.Fatalf("FAILED: expected FetchById to fail over from an unreachable endpoint, got %v", err)
+	}
+	if secondaryHits != 1 {
+		t.Errorf("FAILED: expected the second endpoint to be used, got %d requests", secondaryHits)
+	}
+}
+
+func TestEndpointPool_PostDoesNotFailOver(t *testing.T) {
+	var primaryHits, secondaryHits int32
+	primary := prepareTestEndpointServer(unavailableHandler(&primaryHits))
+	defer primary.Close()
+	secondary := prepareTestEndpointServer(accountHandler(&secondaryHits))
+	defer secondary.Close()
+	accountClient := prepareTestFailoverAccountClient(&ClientSetting{}, primary, secondary)
+
+	_, _, res, _ := accountClient.CreateAccount(populateSingleAccountDataUnitTest())
+	if res == nil || res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("FAILED: expected the 503 of the first endpoint, got %v", res)
+	}
+	if secondaryHits != 0 {
+		t.Errorf("FAILED: expected POST not to fail over, got %d requests", secondaryHits)
+	}
+}
+
+func TestEndpointPool_EjectAndReadmit(t *testing.T) {
+	var primaryHits, secondaryHits int32
+	var primaryDown int32 = 1
+	primary := prepareTestEndpointServer(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&primaryDown) == 1 {
+			unavailableHandler(&primaryHits)(w, r)
+			return
+		}
+		accountHandler(&primaryHits)(w, r)
+	})
+	defer primary.Close()
+	secondary := prepareTestEndpointServer(accountHandler(&secondaryHits))
+	defer secondary.Close()
+	logger := &recordingLogger{}
+	accountClient := prepareTestFailoverAccountClient(&ClientSetting{
+		Logger:        logger,
+		EjectAfter:    2,
+		ProbeInterval: 20 * time.Millisecond,
+	}, primary, secondary)
+	httpClient := accountClient.HttpClient
+
+	for i := 0; i < 2; i++ {
+		accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	}
+	if healthy := httpClient.HealthyBaseURLs(); len(healthy) != 1 || healthy[0] != secondary.URL+UNIT_ACCOUNTS_API_BASE {
+		t.Fatalf("FAILED: expected the first endpoint to be ejected, got %v", healthy)
+	}
+	if logger.find("endpoint ejected") == nil {
+		t.Errorf("FAILED: expected ejection to be logged")
+	}
+
+	before := atomic.LoadInt32(&primaryHits)
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if atomic.LoadInt32(&primaryHits) != before {
+		t.Errorf("FAILED: expected an ejected endpoint not to be tried first")
+	}
+
+	atomic.StoreInt32(&primaryDown, 0)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(httpClient.HealthyBaseURLs()) != 2 && time.Now().Before(deadline) {
+		accountClient.FetchById(SINGLE_ACCOUNT_ID)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if healthy := httpClient.HealthyBaseURLs(); len(healthy) != 2 {
+		t.Fatalf("FAILED: expected the first endpoint to be readmitted after a probe, got %v", healthy)
+	}
+	if logger.find("endpoint readmitted") == nil {
+		t.Errorf("FAILED: expected readmission to be logged")
+	}
+	t.Log("SUCCESS: endpoint was ejected and readmitted")
+}
+
+func TestEndpointPool_RoundRobin(t *testing.T) {
+	var firstHits, secondHits int32
+	first := prepareTestEndpointServer(accountHandler(&firstHits))
+	defer first.Close()
+	second := prepareTestEndpointServer(accountHandler(&secondHits))
+	defer second.Close()
+	accountClient := prepareTestFailoverAccountClient(&ClientSetting{
+		EndpointSelection: ENDPOINT_SELECTION_ROUND_ROBIN,
+	}, first, second)
+
+	for i := 0; i < 4; i++ {
+		if _, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID); err != nil {
+			t.Fatalf("FAILED: FetchById returned error: %v", err)
+		}
+	}
+	if firstHits != 2 || secondHits != 2 {
+		t.Errorf("FAILED: expected requests to be spread evenly, got %d and %d", firstHits, secondHits)
+	}
+}
+
+func TestEndpointPool_PriorityPrefersFirst(t *testing.T) {
+	var firstHits, secondHits int32
+	first := prepareTestEndpointServer(accountHandler(&firstHits))
+	defer first.Close()
+	second := prepareTestEndpointServer(accountHandler(&secondHits))
+	defer second.Close()
+	accountClient := prepareTestFailoverAccountClient(&ClientSetting{}, first, second)
+
+	for i := 0; i < 3; i++ {
+		accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	}
+	if firstHits != 3 || secondHits != 0 {
+		t.Errorf("FAILED: expected all requests on the first endpoint, got %d and %d", firstHits, secondHits)
+	}
+	if baseURL := accountClient.HttpClient.BaseURL; baseURL != first.URL+UNIT_ACCOUNTS_API_BASE {
+		t.Errorf("FAILED: expected BaseURL to default to the first base URL, got %s", baseURL)
+	}
+}
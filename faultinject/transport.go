@@ -0,0 +1,273 @@
+// Package faultinject provides an http.RoundTripper which injects faults into requests by rule,
+// for testing how clients of the account API behave when it is slow or flaky.
+package faultinject
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Fault int
+
+const (
+	// Delays the request by Latency before sending it.
+	FAULT_LATENCY Fault = iota
+	// Fails the request with a connection reset error without sending it.
+	FAULT_CONNECTION_RESET
+	// Holds the request for Latency, or until its context is done when Latency is zero, and fails it with a timeout.
+	FAULT_TIMEOUT
+	// Answers the request with StatusCode and Body without sending it.
+	FAULT_STATUS
+	// Sends the request and cuts the decompressed response body in half, failing the read with io.ErrUnexpectedEOF.
+	FAULT_TRUNCATED_BODY
+	// Sends the request and corrupts the JSON of the decompressed response body.
+	FAULT_MALFORMED_JSON
+)
+
+// Body of injected status responses when the rule has none.
+const INJECTED_BODY_DEFAULT = `{"error_message": "injected fault"}`
+
+// Selects requests and the fault injected into them. All set conditions must hold.
+type Rule struct {
+	// Http method matched, any when empty.
+	Method string
+	// Pattern in path.Match syntax matched against the url path, any when empty.
+	Path string
+	// Chance between 0 and 1 of injecting the fault into a matching request, always when zero.
+	Probability float64
+	// Only injects into the Nth matching request, counted from 1, every one when zero.
+	Nth   int
+	Fault Fault
+	// Delay of FAULT_LATENCY and FAULT_TIMEOUT.
+	Latency    time.Duration
+	StatusCode int
+	Body       string
+}
+
+// Round tripper injecting faults by rule and passing other requests to the next round tripper.
+type Transport struct {
+	next     http.RoundTripper
+	mutex    sync.Mutex
+	rules    []Rule
+	matches  []int
+	random   *rand.Rand
+	injected int
+}
+
+type timeoutError struct{}
+
+type truncatedBody struct {
+	reader io.Reader
+}
+
+// Creates a transport injecting faults by the rules, which are checked in order. The seed makes
+// probabilistic faults reproducible. A nil next round tripper uses http.DefaultTransport.
+func NewTransport(next http.RoundTripper, seed int64, rules ...Rule) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{
+		next:    next,
+		rules:   rules,
+		matches: make([]int, len(rules)),
+		random:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Sends the request, injecting the fault of the first rule which fires.
+func (transport *Transport) RoundTrip(httpRequest *http.Request) (*http.Response, error) {
+	rule := transport.fire(httpRequest)
+	if rule == nil {
+		return transport.next.RoundTrip(httpRequest)
+	}
+
+	switch rule.Fault {
+	case FAULT_CONNECTION_RESET, FAULT_TIMEOUT, FAULT_STATUS:
+		// The request is never sent, so its body is closed as the next round tripper would have.
+		if httpRequest.Body != nil {
+			httpRequest.Body.Close()
+		}
+	}
+
+	switch rule.Fault {
+	case FAULT_LATENCY:
+		if err := sleep(httpRequest.Context(), rule.Latency); err != nil {
+			if httpRequest.Body != nil {
+				httpRequest.Body.Close()
+			}
+			return nil, err
+		}
+		return transport.next.RoundTrip(httpRequest)
+	case FAULT_CONNECTION_RESET:
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	case FAULT_TIMEOUT:
+		if rule.Latency <= 0 {
+			<-httpRequest.Context().Done()
+			return nil, httpRequest.Context().Err()
+		}
+		if err := sleep(httpRequest.Context(), rule.Latency); err != nil {
+			return nil, err
+		}
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}
+	case FAULT_STATUS:
+		return statusResponse(httpRequest, rule), nil
+	}
+
+	httpResponse, err := transport.next.RoundTrip(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	body, err := decodedBody(httpResponse)
+	if err != nil {
+		return nil, err
+	}
+	httpResponse.Header.Del("Content-Length")
+	httpResponse.ContentLength = -1
+	if rule.Fault == FAULT_TRUNCATED_BODY {
+		httpResponse.Body = &truncatedBody{reader: bytes.NewReader(body[:len(body)/2])}
+	} else {
+		httpResponse.Body = ioutil.NopCloser(bytes.NewReader(corrupt(body)))
+	}
+	return httpResponse, nil
+}
+
+// Returns the number of requests faults were injected into.
+func (transport *Transport) Injected() int {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	return transport.injected
+}
+
+// Returns the first rule which fires for the request, counting the request for every matching rule.
+func (transport *Transport) fire(httpRequest *http.Request) *Rule {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	var fired *Rule
+	for i := range transport.rules {
+		rule := &transport.rules[i]
+		if !rule.matches(httpRequest) {
+			continue
+		}
+		transport.matches[i]++
+		if fired != nil || (rule.Nth > 0 && transport.matches[i] != rule.Nth) {
+			continue
+		}
+		if rule.Probability > 0 && transport.random.Float64() >= rule.Probability {
+			continue
+		}
+		fired = rule
+	}
+	if fired != nil {
+		transport.injected++
+	}
+	return fired
+}
+
+// Returns whether the method and path of the request match the rule.
+func (rule *Rule) matches(httpRequest *http.Request) bool {
+	if rule.Method != "" && rule.Method != httpRequest.Method {
+		return false
+	}
+	if rule.Path != "" {
+		matched, err := path.Match(rule.Path, httpRequest.URL.Path)
+		return err == nil && matched
+	}
+	return true
+}
+
+// Builds the injected response of a FAULT_STATUS rule.
+func statusResponse(httpRequest *http.Request, rule *Rule) *http.Response {
+	body := rule.Body
+	if body == "" {
+		body = INJECTED_BODY_DEFAULT
+	}
+	statusCode := rule.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       httpRequest,
+	}
+}
+
+// Reads the response body, decompressing it when gzip encoded so faults apply to the document
+// rather than the compressed bytes. The response is left without content encoding.
+func decodedBody(httpResponse *http.Response) ([]byte, error) {
+	defer httpResponse.Body.Close()
+	if !strings.EqualFold(httpResponse.Header.Get("Content-Encoding"), "gzip") {
+		return ioutil.ReadAll(httpResponse.Body)
+	}
+	reader, err := gzip.NewReader(httpResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	httpResponse.Header.Del("Content-Encoding")
+	httpResponse.Uncompressed = true
+	return ioutil.ReadAll(reader)
+}
+
+// Breaks the JSON document by dropping its last byte and appending an unexpected token.
+func corrupt(body []byte) []byte {
+	if len(body) == 0 {
+		return []byte("{")
+	}
+	return append(append([]byte{}, body[:len(body)-1]...), []byte(",]")...)
+}
+
+// Waits for the duration, or until the context is done.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (timeoutError) Error() string {
+	return "i/o timeout"
+}
+
+func (timeoutError) Timeout() bool {
+	return true
+}
+
+func (timeoutError) Temporary() bool {
+	return true
+}
+
+func (body *truncatedBody) Read(buffer []byte) (int, error) {
+	n, err := body.reader.Read(buffer)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (body *truncatedBody) Close() error {
+	return nil
+}
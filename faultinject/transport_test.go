@@ -0,0 +1,210 @@
+package faultinject
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	client "form3/rest-client"
+)
+
+const ACCOUNT_ID = "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"
+
+const ACCOUNT_RESPONSE = `{"data": {"id": "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc", "type": "accounts"}, "links": {"self": "/v1/organisation/accounts/ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"}}`
+
+func prepareTestFaultyAccountClient(timeout int, seed int64, rules ...Rule) (*client.AccountClient, *Transport, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ACCOUNT_RESPONSE)
+	}))
+	transport := NewTransport(nil, seed, rules...)
+	httpClient := client.NewHttpClient(&client.ClientSetting{
+		BaseURL:   server.URL + "/v1/organisation/accounts",
+		Timeout:   timeout,
+		Transport: transport,
+	})
+	return client.NewAccountClient(httpClient), transport, server.Close
+}
+
+func TestTransport_NoMatchingRule(t *testing.T) {
+	accountClient, transport, closeServer := prepareTestFaultyAccountClient(5000, 1, Rule{Method: "DELETE", Fault: FAULT_CONNECTION_RESET})
+	defer closeServer()
+
+	if _, _, _, err := accountClient.FetchById(ACCOUNT_ID); err != nil {
+		t.Errorf("FAILED: expected requests without matching rule to pass, got %v", err)
+	}
+	if transport.Injected() != 0 {
+		t.Errorf("FAILED: expected no injected faults, got %d", transport.Injected())
+	}
+}
+
+func TestTransport_Latency(t *testing.T) {
+	accountClient, _, closeServer := prepareTestFaultyAccountClient(5000, 1, Rule{Fault: FAULT_LATENCY, Latency: 50 * time.Millisecond})
+	defer closeServer()
+
+	start := time.Now()
+	if _, _, _, err := accountClient.FetchById(ACCOUNT_ID); err != nil {
+		t.Fatalf("FAILED: expected delayed request to succeed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("FAILED: expected at least 50ms latency, took %v", elapsed)
+	}
+	t.Log("SUCCESS: latency was injected")
+}
+
+func TestTransport_ConnectionReset(t *testing.T) {
+	accountClient, _, closeServer := prepareTestFaultyAccountClient(5000, 1, Rule{Path: "/v1/organisation/accounts/*", Fault: FAULT_CONNECTION_RESET})
+	defer closeServer()
+
+	if _, _, _, err := accountClient.FetchById(ACCOUNT_ID); !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("FAILED: expected a connection reset, got %v", err)
+	}
+}
+
+func TestTransport_Timeout(t *testing.T) {
+	accountClient, _, closeServer := prepareTestFaultyAccountClient(30, 1, Rule{Fault: FAULT_TIMEOUT})
+	defer closeServer()
+	if _, _, _, err := accountClient.FetchById(ACCOUNT_ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FAILED: expected the client timeout to expire, got %v", err)
+	}
+
+	accountClient, _, closeServer = prepareTestFaultyAccountClient(5000, 1, Rule{Fault: FAULT_TIMEOUT, Latency: time.Millisecond})
+	defer closeServer()
+	var netError net.Error
+	if _, _, _, err := accountClient.FetchById(ACCOUNT_ID); !errors.As(err, &netError) || !netError.Timeout() {
+		t.Errorf("FAILED: expected a network timeout error, got %v", err)
+	}
+}
+
+func TestTransport_Status(t *testing.T) {
+	accountClient, _, closeServer := prepareTestFaultyAccountClient(5000, 1, Rule{Method: "GET", Fault: FAULT_STATUS, StatusCode: http.StatusServiceUnavailable})
+	defer closeServer()
+
+	_, _, res, err := accountClient.FetchById(ACCOUNT_ID)
+	if res == nil || res.StatusCode != http.StatusServiceUnavailable || err == nil || !strings.Contains(err.Error(), "injected fault") {
+		t.Errorf("FAILED: expected an injected 503 response, got %v %v", res, err)
+	}
+}
+
+func TestTransport_TruncatedBody(t *testing.T) {
+	accountClient, _, closeServer := prepareTestFaultyAccountClient(5000, 1, Rule{Fault: FAULT_TRUNCATED_BODY})
+	defer closeServer()
+
+	if _, _, _, err := accountClient.FetchById(ACCOUNT_ID); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("FAILED: expected an unexpected EOF, got %v", err)
+	}
+}
+
+func TestTransport_MalformedJSON(t *testing.T) {
+	accountClient, _, closeServer := prepareTestFaultyAccountClient(5000, 1, Rule{Fault: FAULT_MALFORMED_JSON})
+	defer closeServer()
+
+	_, _, res, err := accountClient.FetchById(ACCOUNT_ID)
+	if err == nil || res != nil {
+		t.Errorf("FAILED: expected a decode error, got %v %v", res, err)
+	}
+}
+
+func TestTransport_BodyFaultsApplyToDecompressedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "GZIP")
+		writer := gzip.NewWriter(w)
+		fmt.Fprint(writer, ACCOUNT_RESPONSE)
+		writer.Close()
+	}))
+	defer server.Close()
+
+	for _, fault := range []Fault{FAULT_TRUNCATED_BODY, FAULT_MALFORMED_JSON} {
+		httpRequest, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		httpRequest.Header.Set("Accept-Encoding", "gzip")
+		httpResponse, err := NewTransport(nil, 1, Rule{Fault: fault}).RoundTrip(httpRequest)
+		if err != nil {
+			t.Fatalf("FAILED: RoundTrip returned error: %v", err)
+		}
+		body, err := ioutil.ReadAll(httpResponse.Body)
+		if httpResponse.Header.Get("Content-Encoding") != "" || !strings.HasPrefix(ACCOUNT_RESPONSE, string(body[:len(body)/2])) {
+			t.Errorf("FAILED: expected fault %d to apply to the decompressed body, got %q %v", fault, body, err)
+		}
+	}
+}
+
+type closeRecordingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (body *closeRecordingBody) Close() error {
+	body.closed = true
+	return nil
+}
+
+func TestTransport_UnsentRequestBodyIsClosed(t *testing.T) {
+	for _, rule := range []Rule{{Fault: FAULT_CONNECTION_RESET}, {Fault: FAULT_TIMEOUT, Latency: time.Millisecond}, {Fault: FAULT_STATUS}} {
+		body := &closeRecordingBody{Reader: strings.NewReader(ACCOUNT_RESPONSE)}
+		httpRequest, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1:1/", body)
+		NewTransport(nil, 1, rule).RoundTrip(httpRequest)
+		if !body.closed {
+			t.Errorf("FAILED: expected fault %d to close the request body", rule.Fault)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	body := &closeRecordingBody{Reader: strings.NewReader(ACCOUNT_RESPONSE)}
+	httpRequest, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://127.0.0.1:1/", body)
+	NewTransport(nil, 1, Rule{Fault: FAULT_LATENCY, Latency: time.Minute}).RoundTrip(httpRequest)
+	if !body.closed {
+		t.Errorf("FAILED: expected a cancelled latency fault to close the request body")
+	}
+}
+
+func TestTransport_Nth(t *testing.T) {
+	accountClient, transport, closeServer := prepareTestFaultyAccountClient(5000, 1, Rule{Nth: 2, Fault: FAULT_CONNECTION_RESET})
+	defer closeServer()
+
+	var failed []int
+	for i := 1; i <= 3; i++ {
+		if _, _, _, err := accountClient.FetchById(ACCOUNT_ID); err != nil {
+			failed = append(failed, i)
+		}
+	}
+	if len(failed) != 1 || failed[0] != 2 || transport.Injected() != 1 {
+		t.Errorf("FAILED: expected only the second request to fail, got %v", failed)
+	}
+}
+
+func TestTransport_ProbabilityIsDeterministic(t *testing.T) {
+	outcomes := func(seed int64) []bool {
+		accountClient, _, closeServer := prepareTestFaultyAccountClient(5000, seed, Rule{Probability: 0.5, Fault: FAULT_CONNECTION_RESET})
+		defer closeServer()
+		var results []bool
+		for i := 0; i < 20; i++ {
+			_, _, _, err := accountClient.FetchById(ACCOUNT_ID)
+			results = append(results, err != nil)
+		}
+		return results
+	}
+
+	first, second := outcomes(42), outcomes(42)
+	failures := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("FAILED: expected the same faults for the same seed, got %v and %v", first, second)
+		}
+		if first[i] {
+			failures++
+		}
+	}
+	if failures == 0 || failures == 20 {
+		t.Errorf("FAILED: expected some but not all requests to fail, got %d", failures)
+	}
+}
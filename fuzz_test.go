@@ -0,0 +1,173 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const FUZZ_BASE_URL = "http://localhost:8080" + UNIT_ACCOUNTS_API_BASE
+
+func fuzzResponse(status int) *http.Response {
+	request, _ := http.NewRequest("GET", FUZZ_BASE_URL, nil)
+	request.Header.Set(REQUEST_ID_HEADER, SINGLE_ACCOUNT_ID)
+	return &http.Response{StatusCode: status, Header: http.Header{}, Request: request}
+}
+
+func addFuzzBodies(f *testing.F) {
+	for _, body := range []string{
+		SINGLE_ACCOUNT_MOCK_RESPONSE,
+		MULTI_ACCOUNT_MOCK_RESPONSE,
+		WRONG_ACCOUNT_MOCK_RESPONSE,
+		"",
+		"null",
+		`{"data": null, "links": null}`,
+		`{"data": [], "links": {}}`,
+		`{"data": {"version": 1e400}}`,
+		`{"error_message": "iban GB43NWBK40030212764896 rejected"}`,
+	} {
+		f.Add([]byte(body))
+	}
+}
+
+// Decodes the body as a single account envelope and as a list envelope, which must never panic.
+// A successfully decoded account must decode to the same account after being encoded again.
+func FuzzDecodeResponseBody(f *testing.F) {
+	addFuzzBodies(f)
+	httpClient := NewHttpClient(nil)
+	f.Fuzz(func(t *testing.T, body []byte) {
+		account, links := new(AccountData), new(Links)
+		if err := httpClient.decodeResponseBody(fuzzResponse(http.StatusOK), body, account, links); err == nil {
+			encoded, err := json.Marshal(ResponseBody{Data: account, Links: links})
+			if err != nil {
+				t.Fatalf("encoding decoded account: %v", err)
+			}
+			again, againLinks := new(AccountData), new(Links)
+			if err := httpClient.decodeResponseBody(fuzzResponse(http.StatusOK), encoded, again, againLinks); err != nil {
+				t.Fatalf("decoding encoded account %s: %v", encoded, err)
+			}
+			reencoded, _ := json.Marshal(ResponseBody{Data: again, Links: againLinks})
+			if !bytes.Equal(encoded, reencoded) {
+				t.Fatalf("account changed in round trip: %s != %s", encoded, reencoded)
+			}
+		}
+
+		accounts := new([]*AccountData)
+		httpClient.decodeResponseBody(fuzzResponse(http.StatusOK), body, accounts, new(Links))
+		decodeAccountStream(bytes.NewReader(body), new(Links), func(account *AccountData) bool {
+			return true
+		})
+	})
+}
+
+// Decodes the body as an error response, which must never panic. Error messages must be redacted
+// and carry the request ID.
+func FuzzDecodeErrorBody(f *testing.F) {
+	addFuzzBodies(f)
+	httpClient := NewHttpClient(nil)
+	f.Fuzz(func(t *testing.T, body []byte) {
+		err := httpClient.decodeResponseBody(fuzzResponse(http.StatusBadRequest), body, new(AccountData), new(Links))
+		responseError, ok := err.(*ResponseError)
+		if !ok {
+			return
+		}
+		if redacted := httpClient.redactor.String(responseError.Message); redacted != responseError.Message {
+			t.Fatalf("error message not fully redacted: %q", responseError.Message)
+		}
+		if responseError.StatusCode != http.StatusBadRequest || !strings.Contains(responseError.Error(), SINGLE_ACCOUNT_ID) {
+			t.Fatalf("error lost status or request ID: %v", responseError)
+		}
+	})
+}
+
+// Decodes arbitrary links, which must survive an encoding round trip unchanged.
+func FuzzDecodeLinks(f *testing.F) {
+	f.Add(`{"self": "/v1/organisation/accounts?page%5Bnumber%5D=0", "next": "/next"}`)
+	f.Add(`{}`)
+	f.Add(`null`)
+	f.Add(`{"first": 1}`)
+	httpClient := NewHttpClient(nil)
+	f.Fuzz(func(t *testing.T, rawLinks string) {
+		body := []byte(`{"data": {}, "links": ` + rawLinks + `}`)
+		links := new(Links)
+		if err := httpClient.decodeResponseBody(fuzzResponse(http.StatusOK), body, new(AccountData), links); err != nil {
+			return
+		}
+		encoded, _ := json.Marshal(links)
+		again := new(Links)
+		if err := json.Unmarshal(encoded, again); err != nil || !reflect.DeepEqual(links, again) {
+			t.Fatalf("links changed in round trip: %+v != %+v (%v)", links, again, err)
+		}
+	})
+}
+
+// Builds fetch and delete URLs from arbitrary IDs. The ID must stay a single path segment below
+// the base URL and must not be able to add query parameters or fragments.
+func FuzzAccountApiUrl(f *testing.F) {
+	for _, id := range []string{SINGLE_ACCOUNT_ID, "", ".", "..", "../", "../../admin", "x?version=0&y", "a#b", "a/b", "%2e%2e", "é", " "} {
+		f.Add(id, 0)
+	}
+	base, _ := url.Parse(FUZZ_BASE_URL)
+	f.Fuzz(func(t *testing.T, id string, version int) {
+		fetchURL, fetchErr := fetchAccountApiUrl(FUZZ_BASE_URL, id)
+		deleteURL, deleteErr := deleteAccountApiUrl(FUZZ_BASE_URL, id, version)
+		if fetchErr != nil || deleteErr != nil {
+			if id != "" && id != "." && id != ".." {
+				t.Fatalf("id %q rejected: %v %v", id, fetchErr, deleteErr)
+			}
+			return
+		}
+		for _, built := range []string{fetchURL, deleteURL} {
+			parsed, err := url.Parse(built)
+			if err != nil {
+				t.Fatalf("built unparsable url %q: %v", built, err)
+			}
+			if parsed.Host != base.Host || parsed.Fragment != "" {
+				t.Fatalf("id %q changed host or fragment: %q", id, built)
+			}
+			escapedPath := parsed.EscapedPath()
+			if !strings.HasPrefix(escapedPath, base.Path+"/") {
+				t.Fatalf("id %q left the base path: %q", id, built)
+			}
+			segment := strings.TrimPrefix(escapedPath, base.Path+"/")
+			if strings.Contains(segment, "/") || segment == "." || segment == ".." {
+				t.Fatalf("id %q is not a single path segment: %q", id, built)
+			}
+			if unescaped, err := url.PathUnescape(segment); err != nil || unescaped != id {
+				t.Fatalf("id %q did not round trip through %q: %q %v", id, built, unescaped, err)
+			}
+		}
+
+		parsed, _ := url.Parse(deleteURL)
+		if query := parsed.Query(); len(query) != 1 || query.Get("version") != strconv.Itoa(version) {
+			t.Fatalf("id %q changed the delete query: %q", id, deleteURL)
+		}
+	})
+}
+
+// Builds list URLs from arbitrary page parameters, which must not add or change query parameters.
+func FuzzListAccountApiUrl(f *testing.F) {
+	f.Add("0", 100)
+	f.Add("last", 2)
+	f.Add("1&page[size]=1000", 1)
+	f.Add("#", -1)
+	f.Fuzz(func(t *testing.T, number string, size int) {
+		built, err := listAccountApiUrl(FUZZ_BASE_URL, &AccountParams{Number: number, Size: size})
+		if err != nil {
+			t.Fatalf("building list url for page %q: %v", number, err)
+		}
+		parsed, err := url.Parse(built)
+		if err != nil {
+			t.Fatalf("built unparsable url %q: %v", built, err)
+		}
+		query := parsed.Query()
+		if parsed.Fragment != "" || len(query) != 2 || query.Get("page[number]") != number || query.Get("page[size]") != strconv.Itoa(size) {
+			t.Fatalf("page number %q changed the query: %q", number, built)
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+)
+
+// Countries the account generator knows the bank identifier rules of.
+var GENERATOR_COUNTRIES = []string{"GB", "DE", "NL"}
+
+var (
+	generatorFirstNames = []string{"Amelia", "Ben", "Chloe", "Daniel", "Emma", "Finn", "Grace", "Hugo", "Isla", "Jonas", "Lena", "Milan", "Noah", "Olivia", "Sanne", "Thomas"}
+	generatorLastNames  = []string{"Bakker", "Brown", "de Jong", "Evans", "Fischer", "Jansen", "Jones", "Meyer", "Muller", "Schmidt", "Smith", "Taylor", "Visser", "Wagner", "Williams", "Wilson"}
+	generatorLetters    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	generatorDigits     = "0123456789"
+)
+
+// Changes a valid account into a specific invalid variant.
+type AccountMutator func(accountData *AccountData)
+
+// Mutators producing accounts the account API rejects, by the rule they break.
+var INVALID_ACCOUNT_MUTATORS = map[string]AccountMutator{
+	"invalid_id":            MutateInvalidID,
+	"missing_country":       MutateMissingCountry,
+	"invalid_country":       MutateInvalidCountry,
+	"missing_name":          MutateMissingName,
+	"too_many_names":        MutateTooManyNames,
+	"invalid_bic":           MutateInvalidBic,
+	"invalid_base_currency": MutateInvalidBaseCurrency,
+}
+
+// Generates random but valid account data. The same seed produces the same accounts.
+// A generator is not safe for concurrent use.
+type AccountGenerator struct {
+	random *rand.Rand
+}
+
+// Creates an account generator from the seed.
+func NewAccountGenerator(seed int64) *AccountGenerator {
+	return &AccountGenerator{random: rand.New(rand.NewSource(seed))}
+}
+
+// Returns a valid account in a random supported country.
+func (generator *AccountGenerator) Account() *AccountData {
+	return generator.AccountFor(GENERATOR_COUNTRIES[generator.random.Intn(len(GENERATOR_COUNTRIES))])
+}
+
+// Returns a valid account following the bank identifier rules of the country,
+// or of GB when the country is not supported.
+func (generator *AccountGenerator) AccountFor(country string) *AccountData {
+	classification := "Personal"
+	if generator.random.Intn(4) == 0 {
+		classification = "Business"
+	}
+	attributes := &AccountAttributes{
+		AccountClassification: &classification,
+		Name:                  []string{generator.Name()},
+	}
+	switch country {
+	case "DE":
+		attributes.BankID = generator.digits(8)
+		attributes.BankIDCode = "DEBLZ"
+		attributes.AccountNumber = generator.digits(10)
+		attributes.Bic = generator.bic("DE")
+		attributes.BaseCurrency = "EUR"
+		attributes.Iban = Iban("DE", attributes.BankID+attributes.AccountNumber)
+	case "NL":
+		attributes.AccountNumber = generator.digits(10)
+		attributes.Bic = generator.bic("NL")
+		attributes.BaseCurrency = "EUR"
+		attributes.Iban = Iban("NL", attributes.Bic[:4]+attributes.AccountNumber)
+	default:
+		country = "GB"
+		attributes.BankID = generator.digits(6)
+		attributes.BankIDCode = "GBDSC"
+		attributes.AccountNumber = generator.digits(8)
+		attributes.Bic = generator.bic("GB")
+		attributes.BaseCurrency = "GBP"
+		attributes.Iban = Iban("GB", attributes.Bic[:4]+attributes.BankID+attributes.AccountNumber)
+	}
+	attributes.Country = &country
+
+	return &AccountData{
+		ID:             generator.UUID(),
+		OrganisationID: generator.UUID(),
+		Type:           "accounts",
+		Attributes:     attributes,
+	}
+}
+
+// Returns a valid account of the country changed by the mutators.
+func (generator *AccountGenerator) InvalidAccountFor(country string, mutators ...AccountMutator) *AccountData {
+	accountData := generator.AccountFor(country)
+	for _, mutator := range mutators {
+		mutator(accountData)
+	}
+	return accountData
+}
+
+// Returns a random full name.
+func (generator *AccountGenerator) Name() string {
+	return generatorFirstNames[generator.random.Intn(len(generatorFirstNames))] + " " +
+		generatorLastNames[generator.random.Intn(len(generatorLastNames))]
+}
+
+// Returns a random version 4 UUID.
+func (generator *AccountGenerator) UUID() string {
+	b := make([]byte, 16)
+	generator.random.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Returns a BIC of a random bank in the country.
+func (generator *AccountGenerator) bic(country string) string {
+	return generator.pick(generatorLetters, 4) + country + generator.pick(generatorLetters+generatorDigits, 2)
+}
+
+func (generator *AccountGenerator) digits(count int) string {
+	return generator.pick(generatorDigits, count)
+}
+
+func (generator *AccountGenerator) pick(characters string, count int) string {
+	picked := make([]byte, count)
+	for i := range picked {
+		picked[i] = characters[generator.random.Intn(len(characters))]
+	}
+	return string(picked)
+}
+
+// Returns the IBAN of the country and basic bank account number, with check digits computed by ISO 13616.
+func Iban(country string, bban string) string {
+	return country + ibanCheckDigits(country, bban) + bban
+}
+
+// Returns whether the IBAN has valid check digits.
+func ValidIban(iban string) bool {
+	if len(iban) < 5 {
+		return false
+	}
+	return ibanRemainder(iban[4:]+iban[:4]) == 1
+}
+
+// Computes the two check digits making the IBAN remainder modulo 97 equal to one.
+func ibanCheckDigits(country string, bban string) string {
+	return fmt.Sprintf("%02d", 98-ibanRemainder(bban+country+"00"))
+}
+
+// Returns the remainder modulo 97 of the rearranged IBAN with letters converted to numbers.
+func ibanRemainder(rearranged string) int64 {
+	var numeric strings.Builder
+	for _, character := range strings.ToUpper(rearranged) {
+		switch {
+		case character >= '0' && character <= '9':
+			numeric.WriteRune(character)
+		case character >= 'A' && character <= 'Z':
+			numeric.WriteString(fmt.Sprint(character - 'A' + 10))
+		default:
+			return -1
+		}
+	}
+	value, ok := new(big.Int).SetString(numeric.String(), 10)
+	if !ok {
+		return -1
+	}
+	return new(big.Int).Mod(value, big.NewInt(97)).Int64()
+}
+
+// Replaces the account ID with one which is not a UUID.
+func MutateInvalidID(accountData *AccountData) {
+	accountData.ID = "not-a-uuid"
+}
+
+// Removes the country.
+func MutateMissingCountry(accountData *AccountData) {
+	accountData.Attributes.Country = nil
+}
+
+// Replaces the country with a lower case three letter code.
+func MutateInvalidCountry(accountData *AccountData) {
+	country := "gbr"
+	accountData.Attributes.Country = &country
+}
+
+// Removes the account holder names.
+func MutateMissingName(accountData *AccountData) {
+	accountData.Attributes.Name = nil
+}
+
+// Adds more account holder names than the API allows.
+func MutateTooManyNames(accountData *AccountData) {
+	for len(accountData.Attributes.Name) <= 4 {
+		accountData.Attributes.Name = append(accountData.Attributes.Name, "Additional Holder")
+	}
+}
+
+// Replaces the BIC with one of the wrong length.
+func MutateInvalidBic(accountData *AccountData) {
+	accountData.Attributes.Bic = "NWBK"
+}
+
+// Replaces the base currency with one which is not an ISO 4217 code.
+func MutateInvalidBaseCurrency(accountData *AccountData) {
+	accountData.Attributes.BaseCurrency = "pounds"
+}
+
+// Changes the IBAN check digits so the IBAN fails validation, while keeping its shape.
+func MutateInvalidIbanChecksum(accountData *AccountData) {
+	iban := accountData.Attributes.Iban
+	checkDigits := (int(iban[2]-'0')*10 + int(iban[3]-'0') + 1) % 100
+	accountData.Attributes.Iban = fmt.Sprintf("%s%02d%s", iban[:2], checkDigits, iban[4:])
+}
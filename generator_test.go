@@ -0,0 +1,93 @@
+package client
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"form3/rest-client/accountapitest"
+)
+
+var generatorBicPattern = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}$`)
+
+func TestGenerator_SameSeedSameAccounts(t *testing.T) {
+	first, second := NewAccountGenerator(7), NewAccountGenerator(7)
+	for i := 0; i < 10; i++ {
+		if a, b := first.Account(), second.Account(); !reflect.DeepEqual(a, b) {
+			t.Fatalf("FAILED: expected the same account for the same seed, got %+v and %+v", a, b)
+		}
+	}
+	if reflect.DeepEqual(NewAccountGenerator(1).Account(), NewAccountGenerator(2).Account()) {
+		t.Errorf("FAILED: expected different seeds to produce different accounts")
+	}
+}
+
+func TestGenerator_CountryRules(t *testing.T) {
+	cases := []struct {
+		country       string
+		ibanLength    int
+		bankIDLength  int
+		accountLength int
+	}{
+		{"GB", 22, 6, 8},
+		{"DE", 22, 8, 10},
+		{"NL", 18, 0, 10},
+	}
+	generator := NewAccountGenerator(42)
+	for _, testCase := range cases {
+		for i := 0; i < 50; i++ {
+			attributes := generator.AccountFor(testCase.country).Attributes
+			if *attributes.Country != testCase.country || !ValidIban(attributes.Iban) || len(attributes.Iban) != testCase.ibanLength {
+				t.Fatalf("FAILED: expected a valid %s IBAN of length %d, got %s", testCase.country, testCase.ibanLength, attributes.Iban)
+			}
+			if len(attributes.BankID) != testCase.bankIDLength || len(attributes.AccountNumber) != testCase.accountLength {
+				t.Errorf("FAILED: unexpected %s bank id %q or account number %q", testCase.country, attributes.BankID, attributes.AccountNumber)
+			}
+			if !strings.HasSuffix(attributes.Iban, attributes.BankID+attributes.AccountNumber) {
+				t.Errorf("FAILED: expected the %s IBAN %s to embed bank id and account number", testCase.country, attributes.Iban)
+			}
+			if !generatorBicPattern.MatchString(attributes.Bic) || attributes.Bic[4:6] != testCase.country {
+				t.Errorf("FAILED: expected a %s BIC, got %s", testCase.country, attributes.Bic)
+			}
+		}
+	}
+	t.Log("SUCCESS: generated accounts follow the country rules")
+}
+
+func TestGenerator_Iban(t *testing.T) {
+	if iban := Iban("GB", "NWBK60161331926819"); iban != "GB29NWBK60161331926819" {
+		t.Errorf("FAILED: expected the reference IBAN GB29NWBK60161331926819, got %s", iban)
+	}
+	if !ValidIban("DE89370400440532013000") || ValidIban("DE88370400440532013000") || ValidIban("GB") {
+		t.Errorf("FAILED: expected only the correct check digits to validate")
+	}
+
+	accountData := NewAccountGenerator(3).AccountFor("GB")
+	MutateInvalidIbanChecksum(accountData)
+	if ValidIban(accountData.Attributes.Iban) {
+		t.Errorf("FAILED: expected the mutated IBAN %s to fail validation", accountData.Attributes.Iban)
+	}
+}
+
+func TestGenerator_AcceptedAndRejectedByFake(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+	accountClient := NewAccountClient(NewHttpClient(&ClientSetting{BaseURL: server.BaseURL, Timeout: INTEGRATION_TIME_OUT}))
+	generator := NewAccountGenerator(99)
+
+	for i := 0; i < 20; i++ {
+		if _, _, _, err := accountClient.CreateAccount(generator.Account()); err != nil {
+			t.Errorf("FAILED: expected a generated account to be accepted, got %v", err)
+		}
+	}
+	for name, mutator := range INVALID_ACCOUNT_MUTATORS {
+		for _, country := range GENERATOR_COUNTRIES {
+			_, _, res, err := accountClient.CreateAccount(generator.InvalidAccountFor(country, mutator))
+			if res == nil || res.StatusCode != http.StatusBadRequest {
+				t.Errorf("FAILED: expected %s account mutated by %s to be rejected, got %v %v", country, name, res, err)
+			}
+		}
+	}
+}
@@ -1,3 +1,3 @@
 module form3/rest-client
 
-go 1.17
+go 1.18
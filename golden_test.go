@@ -0,0 +1,167 @@
+package client
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files under testdata/golden")
+
+// Directory of the golden files holding the requests sent by each account client operation.
+const GOLDEN_DIRECTORY = "testdata/golden"
+
+// Headers whose values differ on every request, replaced by placeholders in golden files.
+var GOLDEN_NORMALIZED_HEADERS = map[string]string{
+	REQUEST_ID_HEADER:  "<request-id>",
+	TRACEPARENT_HEADER: "<traceparent>",
+}
+
+// Transport recording the requests it is given and answering them with a canned response.
+type goldenTransport struct {
+	requests []*http.Request
+	bodies   [][]byte
+	status   int
+	body     string
+}
+
+func (transport *goldenTransport) RoundTrip(httpRequest *http.Request) (*http.Response, error) {
+	var body []byte
+	if httpRequest.Body != nil {
+		body, _ = ioutil.ReadAll(httpRequest.Body)
+		httpRequest.Body.Close()
+	}
+	transport.requests = append(transport.requests, httpRequest)
+	transport.bodies = append(transport.bodies, body)
+	return &http.Response{
+		StatusCode: transport.status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(transport.body)),
+		Request:    httpRequest,
+	}, nil
+}
+
+// Formats the recorded requests as method and URL, headers sorted by name and the body as sent.
+func (transport *goldenTransport) format() []byte {
+	var buffer bytes.Buffer
+	for i, httpRequest := range transport.requests {
+		fmt.Fprintf(&buffer, "%s %s\n", httpRequest.Method, httpRequest.URL.String())
+		names := make([]string, 0, len(httpRequest.Header))
+		for name := range httpRequest.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range httpRequest.Header[name] {
+				fmt.Fprintf(&buffer, "%s: %s\n", name, normalizeGoldenHeader(name, value))
+			}
+		}
+		fmt.Fprintf(&buffer, "\n%s\n", transport.bodies[i])
+	}
+	return buffer.Bytes()
+}
+
+// Returns the placeholder of headers which differ on every request, or the value otherwise.
+func normalizeGoldenHeader(name string, value string) string {
+	for normalized, placeholder := range GOLDEN_NORMALIZED_HEADERS {
+		if strings.EqualFold(name, normalized) {
+			return placeholder
+		}
+	}
+	return value
+}
+
+// Compares the requests recorded by the transport with the golden file, rewriting it with -update.
+func assertGolden(t *testing.T, name string, transport *goldenTransport) {
+	t.Helper()
+	actual := transport.format()
+	golden := filepath.Join(GOLDEN_DIRECTORY, name+".golden")
+	if *updateGolden {
+		if err := os.MkdirAll(GOLDEN_DIRECTORY, 0755); err != nil {
+			t.Fatalf("FAILED: creating golden directory: %v", err)
+		}
+		if err := ioutil.WriteFile(golden, actual, 0644); err != nil {
+			t.Fatalf("FAILED: writing golden file: %v", err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("FAILED: reading golden file, run with -update to create it: %v", err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("FAILED: requests differ from %s, run with -update if the change is intended\nexpected:\n%s\ngot:\n%s", golden, expected, actual)
+	} else {
+		t.Logf("SUCCESS: requests match %s", golden)
+	}
+}
+
+// Creates an account client with the default setting sending its requests to a golden transport.
+// A nil tracer keeps the default one.
+func prepareGoldenAccountClient(status int, body string, tracer Tracer) (*AccountClient, *goldenTransport) {
+	transport := &goldenTransport{status: status, body: body}
+	httpClient := NewHttpClient(&ClientSetting{
+		BaseURL:   CLIENT_SETTING_DEFAULT.BaseURL,
+		Timeout:   INTEGRATION_TIME_OUT,
+		Tracer:    tracer,
+		Transport: transport,
+	})
+	return NewAccountClient(httpClient), transport
+}
+
+func TestGolden_AccountRequests(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    int
+		body      string
+		tracer    Tracer
+		operation func(accountClient *AccountClient) error
+	}{
+		{"create_account", http.StatusCreated, SINGLE_ACCOUNT_MOCK_RESPONSE, nil, func(accountClient *AccountClient) error {
+			_, _, _, err := accountClient.CreateAccount(populateSingleAccountDataUnitTest())
+			return err
+		}},
+		{"fetch_by_id", http.StatusOK, SINGLE_ACCOUNT_MOCK_RESPONSE, nil, func(accountClient *AccountClient) error {
+			_, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+			return err
+		}},
+		{"fetch_by_id_traced", http.StatusOK, SINGLE_ACCOUNT_MOCK_RESPONSE, NewInMemoryTracer(), func(accountClient *AccountClient) error {
+			_, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+			return err
+		}},
+		{"list_account", http.StatusOK, MULTI_ACCOUNT_MOCK_RESPONSE, nil, func(accountClient *AccountClient) error {
+			_, _, _, err := accountClient.ListAccount(nil)
+			return err
+		}},
+		{"list_account_with_params", http.StatusOK, MULTI_ACCOUNT_MOCK_RESPONSE, nil, func(accountClient *AccountClient) error {
+			_, _, _, err := accountClient.ListAccount(&AccountParams{Number: "1", Size: 2})
+			return err
+		}},
+		{"list_account_stream", http.StatusOK, MULTI_ACCOUNT_MOCK_RESPONSE, nil, func(accountClient *AccountClient) error {
+			_, _, err := accountClient.ListAccountStream(&AccountParams{Number: "0", Size: 2}, func(account *AccountData) error {
+				return nil
+			})
+			return err
+		}},
+		{"delete_account", http.StatusNoContent, "", nil, func(accountClient *AccountClient) error {
+			_, err := accountClient.DeleteAccount(SINGLE_ACCOUNT_ID, 0)
+			return err
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			accountClient, transport := prepareGoldenAccountClient(c.status, c.body, c.tracer)
+			if err := c.operation(accountClient); err != nil {
+				t.Fatalf("FAILED: %s returned error: %v", c.name, err)
+			}
+			assertGolden(t, c.name, transport)
+		})
+	}
+}
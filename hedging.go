@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Number of recent latencies the percentile based hedging delay is derived from.
+const HEDGING_LATENCY_SAMPLES = 256
+
+// Number of recent requests the hedge rate is measured over.
+const HEDGING_RATE_WINDOW = 100
+
+// Configures request hedging for idempotent GET requests. After the delay a second request is
+// sent and whichever completes first is used, cancelling the other.
+type HedgingPolicy struct {
+	// Fixed delay before the hedged request is sent, also used until enough latencies are known.
+	Delay time.Duration
+	// Percentile of recent latencies, between 0 and 1, used as delay once MinSamples are known.
+	Percentile float64
+	// Number of latencies needed before the percentile delay is used.
+	MinSamples int
+	// Maximum share of the last HEDGING_RATE_WINDOW requests which may be hedged.
+	MaxHedgeRatio float64
+}
+
+var HEDGING_POLICY_DEFAULT = &HedgingPolicy{
+	Delay:         50 * time.Millisecond,
+	MinSamples:    20,
+	MaxHedgeRatio: 0.1,
+}
+
+type hedger struct {
+	mutex     sync.Mutex
+	policy    HedgingPolicy
+	latencies []time.Duration
+	next      int
+	hedged    []bool
+	position  int
+}
+
+type attemptResult struct {
+	httpResponse  *http.Response
+	responseBytes []byte
+	err           error
+	start         time.Time
+}
+
+// Creates a hedger, filling unset values from the default policy.
+func newHedger(policy *HedgingPolicy) *hedger {
+	merged := *policy
+	if merged.Delay <= 0 {
+		merged.Delay = HEDGING_POLICY_DEFAULT.Delay
+	}
+	if merged.MinSamples <= 0 {
+		merged.MinSamples = HEDGING_POLICY_DEFAULT.MinSamples
+	}
+	if merged.MaxHedgeRatio <= 0 {
+		merged.MaxHedgeRatio = HEDGING_POLICY_DEFAULT.MaxHedgeRatio
+	}
+	return &hedger{policy: merged}
+}
+
+// Returns the delay before a hedged request is sent.
+func (hedger *hedger) delay() time.Duration {
+	hedger.mutex.Lock()
+	defer hedger.mutex.Unlock()
+	if hedger.policy.Percentile <= 0 || len(hedger.latencies) < hedger.policy.MinSamples {
+		return hedger.policy.Delay
+	}
+	sorted := append([]time.Duration{}, hedger.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(hedger.policy.Percentile * float64(len(sorted)))
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+// Records the latency of a completed request.
+func (hedger *hedger) observe(latency time.Duration) {
+	hedger.mutex.Lock()
+	defer hedger.mutex.Unlock()
+	if len(hedger.latencies) < HEDGING_LATENCY_SAMPLES {
+		hedger.latencies = append(hedger.latencies, latency)
+		return
+	}
+	hedger.latencies[hedger.next] = latency
+	hedger.next = (hedger.next + 1) % HEDGING_LATENCY_SAMPLES
+}
+
+// Records whether a request was slow enough to be hedged, returning whether the hedge is allowed
+// without exceeding the maximum hedge ratio over the recent requests.
+func (hedger *hedger) reserve(slow bool) bool {
+	hedger.mutex.Lock()
+	defer hedger.mutex.Unlock()
+	allowed := false
+	if slow {
+		hedges := 1
+		for _, hedged := range hedger.hedged {
+			if hedged {
+				hedges++
+			}
+		}
+		allowed = float64(hedges) <= hedger.policy.MaxHedgeRatio*HEDGING_RATE_WINDOW
+	}
+	if len(hedger.hedged) < HEDGING_RATE_WINDOW {
+		hedger.hedged = append(hedger.hedged, allowed)
+	} else {
+		hedger.hedged[hedger.position] = allowed
+		hedger.position = (hedger.position + 1) % HEDGING_RATE_WINDOW
+	}
+	return allowed
+}
+
+// Performs the GET request, sending a second attempt when the first is slower than the hedging
+// delay. Whichever attempt completes first wins and the other is cancelled.
+// Returns http response and response body.
+func (httpClient *HttpClient) hedge(httpRequest *http.Request, route string, attempts *int32) (*http.Response, []byte, error) {
+	ctx := httpRequest.Context()
+	results := make(chan attemptResult, 2)
+	launch := func() context.CancelFunc {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		attempt := nextAttempt(attempts)
+		go func() {
+			start := time.Now()
+			httpResponse, responseBytes, err := httpClient.attempt(httpRequest.WithContext(attemptCtx), attempt)
+			results <- attemptResult{httpResponse: httpResponse, responseBytes: responseBytes, err: err, start: start}
+		}()
+		return cancel
+	}
+
+	cancelPrimary := launch()
+	defer cancelPrimary()
+
+	timer := time.NewTimer(httpClient.hedger.delay())
+	defer timer.Stop()
+	select {
+	case result := <-results:
+		httpClient.hedger.reserve(false)
+		httpClient.hedger.observe(time.Since(result.start))
+		return result.httpResponse, result.responseBytes, result.err
+	case <-timer.C:
+	}
+
+	if !httpClient.hedger.reserve(true) {
+		result := <-results
+		httpClient.hedger.observe(time.Since(result.start))
+		return result.httpResponse, result.responseBytes, result.err
+	}
+
+	httpClient.metrics.RequestHedged(httpRequest.Method, route)
+	httpClient.logger.Log(LOG_LEVEL_DEBUG, "request hedged",
+		Field("method", httpRequest.Method), Field("url", httpRequest.URL.String()),
+		Field("request_id", httpRequest.Header.Get(REQUEST_ID_HEADER)))
+	cancelHedge := launch()
+	defer cancelHedge()
+
+	result := <-results
+	if result.err != nil && !isResponseError(result.err) && ctx.Err() == nil {
+		// The first attempt failed outright, so the other one may still succeed.
+		result = <-results
+	}
+	httpClient.hedger.observe(time.Since(result.start))
+	return result.httpResponse, result.responseBytes, result.err
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedging_SecondRequestWins(t *testing.T) {
+	var hits int32
+	cancelled := make(chan struct{}, 1)
+	collector := NewPrometheusCollector(nil)
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{Hedging: &HedgingPolicy{Delay: 20 * time.Millisecond}, Metrics: collector},
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&hits, 1) == 1 {
+				select {
+				case <-r.Context().Done():
+					cancelled <- struct{}{}
+				case <-time.After(2 * time.Second):
+				}
+				return
+			}
+			fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+		})
+	defer closeServer()
+
+	start := time.Now()
+	account, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if err != nil || account.ID != SINGLE_ACCOUNT_ID {
+		t.Fatalf("FAILED: expected hedged FetchById to succeed, got %v %v", account, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("FAILED: expected hedged request to finish early, took %v", elapsed)
+	}
+	if atomic.LoadInt32(&hits) != 2 {
+		t.Errorf("FAILED: expected 2 requests, got %d", hits)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("FAILED: expected the slow request to be cancelled")
+	}
+	key := metricKey{method: "GET", route: "/v1/organisation/accounts/{id}"}
+	if collector.hedges[key] != 1 || collector.retries[key] != 0 {
+		t.Errorf("FAILED: expected the hedge to be counted as hedge only, got hedges %v retries %v", collector.hedges, collector.retries)
+	}
+}
+
+func TestHedging_FastRequestNotHedged(t *testing.T) {
+	var hits int32
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{Hedging: &HedgingPolicy{Delay: 200 * time.Millisecond}},
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+		})
+	defer closeServer()
+
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("FAILED: expected a single request, got %d", hits)
+	}
+}
+
+func TestHedging_PostNotHedged(t *testing.T) {
+	var hits int32
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{Hedging: &HedgingPolicy{Delay: time.Millisecond}},
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			time.Sleep(30 * time.Millisecond)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+		})
+	defer closeServer()
+
+	accountClient.CreateAccount(populateSingleAccountDataUnitTest())
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("FAILED: expected POST not to be hedged, got %d requests", hits)
+	}
+}
+
+func TestHedging_PercentileDelay(t *testing.T) {
+	hedger := newHedger(&HedgingPolicy{Delay: time.Second, Percentile: 0.9, MinSamples: 10})
+	for i := 1; i <= 9; i++ {
+		hedger.observe(time.Duration(i) * time.Millisecond)
+	}
+	if delay := hedger.delay(); delay != time.Second {
+		t.Errorf("FAILED: expected fixed delay before enough samples, got %v", delay)
+	}
+	hedger.observe(10 * time.Millisecond)
+	if delay := hedger.delay(); delay != 10*time.Millisecond {
+		t.Errorf("FAILED: expected p90 delay of 10ms, got %v", delay)
+	}
+}
+
+func TestHedging_MaxHedgeRatio(t *testing.T) {
+	hedger := newHedger(&HedgingPolicy{MaxHedgeRatio: 0.02})
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if hedger.reserve(true) {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("FAILED: expected 2 hedges within the ratio, got %d", allowed)
+	}
+}
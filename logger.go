@@ -0,0 +1,113 @@
+package client
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+type LogLevel int
+
+const (
+	LOG_LEVEL_DEBUG LogLevel = iota
+	LOG_LEVEL_INFO
+	LOG_LEVEL_WARN
+	LOG_LEVEL_ERROR
+)
+
+// Structured key value pair attached to a log event.
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// Receives levelled, structured log events emitted by the client.
+type Logger interface {
+	Log(level LogLevel, message string, fields ...LogField)
+}
+
+// Adapts an ordinary function to the Logger interface.
+type LoggerFunc func(level LogLevel, message string, fields ...LogField)
+
+type noopLogger struct{}
+
+type stdLogger struct {
+	logger   *log.Logger
+	minLevel LogLevel
+}
+
+// Returns the lower case name of the log level.
+func (level LogLevel) String() string {
+	switch level {
+	case LOG_LEVEL_DEBUG:
+		return "debug"
+	case LOG_LEVEL_INFO:
+		return "info"
+	case LOG_LEVEL_WARN:
+		return "warn"
+	case LOG_LEVEL_ERROR:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(level))
+}
+
+// Creates a log field from key and value.
+func Field(key string, value interface{}) LogField {
+	return LogField{Key: key, Value: value}
+}
+
+// Calls the wrapped function with the log event.
+func (loggerFunc LoggerFunc) Log(level LogLevel, message string, fields ...LogField) {
+	loggerFunc(level, message, fields...)
+}
+
+// Creates a logger which discards every event. It is used when no logger is configured.
+func NewNoopLogger() Logger {
+	return noopLogger{}
+}
+
+func (noopLogger) Log(level LogLevel, message string, fields ...LogField) {}
+
+// Creates a logger writing events at or above the minimum level to a standard library logger,
+// formatted as `level=... msg=... key=value`. A nil logger writes to the standard logger.
+func NewStdLogger(logger *log.Logger, minLevel LogLevel) Logger {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &stdLogger{
+		logger:   logger,
+		minLevel: minLevel,
+	}
+}
+
+func (stdLogger *stdLogger) Log(level LogLevel, message string, fields ...LogField) {
+	if level < stdLogger.minLevel {
+		return
+	}
+	stdLogger.logger.Print(formatLogEvent(level, message, fields))
+}
+
+// Formats a log event as a single logfmt style line.
+func formatLogEvent(level LogLevel, message string, fields []LogField) string {
+	var builder strings.Builder
+	builder.WriteString("level=")
+	builder.WriteString(level.String())
+	builder.WriteString(" msg=")
+	builder.WriteString(formatLogValue(message))
+	for _, field := range fields {
+		builder.WriteString(" ")
+		builder.WriteString(field.Key)
+		builder.WriteString("=")
+		builder.WriteString(formatLogValue(field.Value))
+	}
+	return builder.String()
+}
+
+// Formats a field value, quoting it when it contains spaces or quotes.
+func formatLogValue(value interface{}) string {
+	text := fmt.Sprint(value)
+	if text == "" || strings.ContainsAny(text, " \t\n\"=") {
+		return fmt.Sprintf("%q", text)
+	}
+	return text
+}
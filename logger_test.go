@@ -0,0 +1,119 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type logEvent struct {
+	level   LogLevel
+	message string
+	fields  map[string]interface{}
+}
+
+type recordingLogger struct {
+	mutex  sync.Mutex
+	events []logEvent
+}
+
+func (recorder *recordingLogger) Log(level LogLevel, message string, fields ...LogField) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	event := logEvent{level: level, message: message, fields: map[string]interface{}{}}
+	for _, field := range fields {
+		event.fields[field.Key] = field.Value
+	}
+	recorder.events = append(recorder.events, event)
+}
+
+func (recorder *recordingLogger) find(message string) *logEvent {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	for i := range recorder.events {
+		if recorder.events[i].message == message {
+			return &recorder.events[i]
+		}
+	}
+	return nil
+}
+
+func TestLogger_RequestEvents(t *testing.T) {
+	logger := &recordingLogger{}
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{Logger: logger},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+		})
+	defer closeServer()
+
+	_, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if err != nil {
+		t.Fatalf("FAILED: FetchById returned error: %v", err)
+	}
+
+	started := logger.find("request started")
+	if started == nil || started.level != LOG_LEVEL_DEBUG {
+		t.Fatalf("FAILED: expected debug request started event, got %+v", logger.events)
+	}
+	finished := logger.find("request finished")
+	if finished == nil {
+		t.Fatalf("FAILED: expected request finished event, got %+v", logger.events)
+	}
+	if finished.fields["method"] != "GET" || finished.fields["status"] != http.StatusOK || finished.fields["attempt"] != 1 {
+		t.Errorf("FAILED: unexpected request finished fields %+v", finished.fields)
+	}
+	if !strings.HasSuffix(fmt.Sprint(finished.fields["url"]), SINGLE_ACCOUNT_ID) {
+		t.Errorf("FAILED: expected url field to end with account id, got %v", finished.fields["url"])
+	}
+	if _, ok := finished.fields["duration"]; !ok {
+		t.Errorf("FAILED: expected duration field, got %+v", finished.fields)
+	}
+}
+
+func TestLogger_AccountClientError(t *testing.T) {
+	logger := &recordingLogger{}
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{Logger: logger},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, WRONG_ACCOUNT_MOCK_RESPONSE)
+		})
+	defer closeServer()
+
+	_, _, _, err := accountClient.FetchById(WRONG_ACCOUNT_ID)
+	if err == nil {
+		t.Fatalf("FAILED: FetchById expected error, got nil")
+	}
+
+	event := logger.find("Error occurred while fetching account by id")
+	if event == nil || event.level != LOG_LEVEL_ERROR {
+		t.Fatalf("FAILED: expected error event, got %+v", logger.events)
+	}
+	if event.fields["id"] != WRONG_ACCOUNT_ID {
+		t.Errorf("FAILED: expected id field %v, got %v", WRONG_ACCOUNT_ID, event.fields["id"])
+	}
+}
+
+func TestLogger_NoopByDefault(t *testing.T) {
+	httpClient := NewHttpClient(nil)
+	if _, ok := httpClient.logger.(noopLogger); !ok {
+		t.Errorf("FAILED: expected no-op logger by default, got %T", httpClient.logger)
+	}
+}
+
+func TestLogger_StdLogger(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := NewStdLogger(log.New(buffer, "", 0), LOG_LEVEL_INFO)
+
+	logger.Log(LOG_LEVEL_DEBUG, "hidden")
+	logger.Log(LOG_LEVEL_ERROR, "request failed", Field("method", "GET"), Field("error", "connection refused"))
+
+	expected := "level=error msg=\"request failed\" method=GET error=\"connection refused\"\n"
+	if buffer.String() != expected {
+		t.Errorf("FAILED: std logger expected %q, got %q", expected, buffer.String())
+	}
+}
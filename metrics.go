@@ -0,0 +1,315 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ERROR_CLASS_NONE         = ""
+	ERROR_CLASS_TIMEOUT      = "timeout"
+	ERROR_CLASS_CANCELED     = "canceled"
+	ERROR_CLASS_NETWORK      = "network"
+	ERROR_CLASS_CLIENT_ERROR = "client_error"
+	ERROR_CLASS_SERVER_ERROR = "server_error"
+	ERROR_CLASS_DECODE       = "decode"
+	ERROR_CLASS_CIRCUIT_OPEN = "circuit_open"
+)
+
+// Histogram buckets in seconds used when none are configured.
+var METRICS_BUCKETS_DEFAULT = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+var routeIdentifierPattern = regexp.MustCompile(`^([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9]+)$`)
+
+// Receives request measurements from the client. Routes are templates such as
+// `/v1/organisation/accounts/{id}` so that label cardinality stays bounded.
+type MetricsCollector interface {
+	RequestStarted(method, route string)
+	RequestFinished(method, route string, status int, duration time.Duration, errorClass string)
+	// Reported for every additional attempt of a request, such as a failover to another base URL.
+	RequestRetried(method, route string)
+	// Reported for every hedged attempt of a request sent while an earlier attempt is still running.
+	RequestHedged(method, route string)
+}
+
+type noopMetricsCollector struct{}
+
+// Collects client metrics in memory and exposes them in the Prometheus text format.
+// It implements http.Handler, so it can be mounted on a `/metrics` endpoint.
+type PrometheusCollector struct {
+	mutex     sync.Mutex
+	buckets   []float64
+	requests  map[metricKey]float64
+	errors    map[metricKey]float64
+	retries   map[metricKey]float64
+	hedges    map[metricKey]float64
+	inFlight  map[metricKey]float64
+	durations map[metricKey]*histogram
+}
+
+type metricKey struct {
+	method string
+	route  string
+	label  string
+}
+
+type histogram struct {
+	counts []float64
+	sum    float64
+	count  float64
+}
+
+// Creates a metrics collector which discards every measurement. It is used when no collector is configured.
+func NewNoopMetricsCollector() MetricsCollector {
+	return noopMetricsCollector{}
+}
+
+func (noopMetricsCollector) RequestStarted(method, route string) {}
+
+func (noopMetricsCollector) RequestFinished(method, route string, status int, duration time.Duration, errorClass string) {
+}
+
+func (noopMetricsCollector) RequestRetried(method, route string) {}
+
+func (noopMetricsCollector) RequestHedged(method, route string) {}
+
+// Creates a Prometheus collector using the histogram buckets in seconds, or the default buckets when empty.
+func NewPrometheusCollector(buckets []float64) *PrometheusCollector {
+	if len(buckets) == 0 {
+		buckets = METRICS_BUCKETS_DEFAULT
+	}
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return &PrometheusCollector{
+		buckets:   sorted,
+		requests:  map[metricKey]float64{},
+		errors:    map[metricKey]float64{},
+		retries:   map[metricKey]float64{},
+		hedges:    map[metricKey]float64{},
+		inFlight:  map[metricKey]float64{},
+		durations: map[metricKey]*histogram{},
+	}
+}
+
+func (collector *PrometheusCollector) RequestStarted(method, route string) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.inFlight[metricKey{method: method, route: route}]++
+}
+
+func (collector *PrometheusCollector) RequestFinished(method, route string, status int, duration time.Duration, errorClass string) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	key := metricKey{method: method, route: route}
+	collector.inFlight[key]--
+	collector.requests[metricKey{method: method, route: route, label: statusLabel(status)}]++
+	if errorClass != ERROR_CLASS_NONE {
+		collector.errors[metricKey{method: method, route: route, label: errorClass}]++
+	}
+
+	durationKey := metricKey{method: method, route: route, label: statusLabel(status)}
+	durationHistogram := collector.durations[durationKey]
+	if durationHistogram == nil {
+		durationHistogram = &histogram{counts: make([]float64, len(collector.buckets))}
+		collector.durations[durationKey] = durationHistogram
+	}
+	seconds := duration.Seconds()
+	for i, bucket := range collector.buckets {
+		if seconds <= bucket {
+			durationHistogram.counts[i]++
+		}
+	}
+	durationHistogram.sum += seconds
+	durationHistogram.count++
+}
+
+func (collector *PrometheusCollector) RequestRetried(method, route string) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.retries[metricKey{method: method, route: route}]++
+}
+
+func (collector *PrometheusCollector) RequestHedged(method, route string) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.hedges[metricKey{method: method, route: route}]++
+}
+
+// Writes every metric in the Prometheus text exposition format.
+func (collector *PrometheusCollector) WriteTo(w io.Writer) (int64, error) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	var builder strings.Builder
+	writeCounter(&builder, "rest_client_requests_total", "Total number of requests by status.", "status", collector.requests)
+	writeCounter(&builder, "rest_client_errors_total", "Total number of failed requests by error class.", "class", collector.errors)
+	writeCounter(&builder, "rest_client_retries_total", "Total number of retried request attempts.", "", collector.retries)
+	writeCounter(&builder, "rest_client_hedges_total", "Total number of hedged request attempts.", "", collector.hedges)
+
+	builder.WriteString("# HELP rest_client_requests_in_flight Number of requests currently in flight.\n")
+	builder.WriteString("# TYPE rest_client_requests_in_flight gauge\n")
+	for _, key := range sortedMetricKeys(collector.inFlight) {
+		fmt.Fprintf(&builder, "rest_client_requests_in_flight{%s} %s\n", key.labels(""), formatMetricValue(collector.inFlight[key]))
+	}
+
+	builder.WriteString("# HELP rest_client_request_duration_seconds Request latency in seconds.\n")
+	builder.WriteString("# TYPE rest_client_request_duration_seconds histogram\n")
+	durationKeys := make([]metricKey, 0, len(collector.durations))
+	for key := range collector.durations {
+		durationKeys = append(durationKeys, key)
+	}
+	sortMetricKeys(durationKeys)
+	for _, key := range durationKeys {
+		durationHistogram := collector.durations[key]
+		labels := key.labels("status")
+		for i, bucket := range collector.buckets {
+			fmt.Fprintf(&builder, "rest_client_request_duration_seconds_bucket{%s,le=\"%s\"} %s\n",
+				labels, formatMetricValue(bucket), formatMetricValue(durationHistogram.counts[i]))
+		}
+		fmt.Fprintf(&builder, "rest_client_request_duration_seconds_bucket{%s,le=\"+Inf\"} %s\n", labels, formatMetricValue(durationHistogram.count))
+		fmt.Fprintf(&builder, "rest_client_request_duration_seconds_sum{%s} %s\n", labels, formatMetricValue(durationHistogram.sum))
+		fmt.Fprintf(&builder, "rest_client_request_duration_seconds_count{%s} %s\n", labels, formatMetricValue(durationHistogram.count))
+	}
+
+	written, err := io.WriteString(w, builder.String())
+	return int64(written), err
+}
+
+// Serves the metrics in the Prometheus text exposition format.
+func (collector *PrometheusCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = collector.WriteTo(w)
+}
+
+func writeCounter(builder *strings.Builder, name, help, labelName string, values map[metricKey]float64) {
+	fmt.Fprintf(builder, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(builder, "# TYPE %s counter\n", name)
+	for _, key := range sortedMetricKeys(values) {
+		fmt.Fprintf(builder, "%s{%s} %s\n", name, key.labels(labelName), formatMetricValue(values[key]))
+	}
+}
+
+func sortedMetricKeys(values map[metricKey]float64) []metricKey {
+	keys := make([]metricKey, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sortMetricKeys(keys)
+	return keys
+}
+
+func sortMetricKeys(keys []metricKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].label < keys[j].label
+	})
+}
+
+// Formats the metric key as Prometheus labels, adding the extra label when its name is given.
+func (key metricKey) labels(labelName string) string {
+	labels := fmt.Sprintf(`method="%s",route="%s"`, escapeLabelValue(key.method), escapeLabelValue(key.route))
+	if labelName != "" {
+		labels += fmt.Sprintf(`,%s="%s"`, labelName, escapeLabelValue(key.label))
+	}
+	return labels
+}
+
+// Escapes a label value for the Prometheus text format, which only escapes backslash,
+// double quote and line feed.
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatMetricValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func statusLabel(status int) string {
+	if status == 0 {
+		return "none"
+	}
+	return strconv.Itoa(status)
+}
+
+// Returns the route template of the request URL, replacing every path segment below the
+// base URL, as well as UUID and numeric segments elsewhere, with `{id}`.
+func routeTemplate(baseURL string, requestURL *url.URL) string {
+	basePath := ""
+	if parsed, err := url.Parse(baseURL); err == nil && parsed.Host == requestURL.Host {
+		basePath = strings.TrimSuffix(parsed.Path, "/")
+	}
+
+	path := requestURL.EscapedPath()
+	if basePath != "" && (path == basePath || strings.HasPrefix(path, basePath+"/")) {
+		remainder := strings.TrimPrefix(path, basePath)
+		segments := strings.Split(remainder, "/")
+		for i := 1; i < len(segments); i++ {
+			segments[i] = "{id}"
+		}
+		return basePath + strings.Join(segments, "/")
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if routeIdentifierPattern.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Classifies the outcome of a request by its response status, 0 when there was no response, and
+// its error. Returns one of the ERROR_CLASS constants, as reported to the error metrics.
+func ClassifyError(status int, err error) string {
+	var responseError *ResponseError
+	var netError net.Error
+	switch {
+	case err == nil || errors.As(err, &responseError):
+		if status >= 500 {
+			return ERROR_CLASS_SERVER_ERROR
+		}
+		if status >= 400 {
+			return ERROR_CLASS_CLIENT_ERROR
+		}
+		if err != nil {
+			return ERROR_CLASS_CLIENT_ERROR
+		}
+		return ERROR_CLASS_NONE
+	case errors.Is(err, ErrCircuitOpen):
+		return ERROR_CLASS_CIRCUIT_OPEN
+	case errors.Is(err, context.Canceled):
+		return ERROR_CLASS_CANCELED
+	case errors.Is(err, context.DeadlineExceeded):
+		return ERROR_CLASS_TIMEOUT
+	case errors.As(err, &netError):
+		if netError.Timeout() {
+			return ERROR_CLASS_TIMEOUT
+		}
+		return ERROR_CLASS_NETWORK
+	case status != 0:
+		return ERROR_CLASS_DECODE
+	}
+	return ERROR_CLASS_NETWORK
+}
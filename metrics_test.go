@@ -0,0 +1,130 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_PrometheusExposition(t *testing.T) {
+	collector := NewPrometheusCollector([]float64{0.5, 1})
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{Metrics: collector},
+		func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, WRONG_ACCOUNT_ID) {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, WRONG_ACCOUNT_MOCK_RESPONSE)
+				return
+			}
+			fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+		})
+	defer closeServer()
+
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	accountClient.FetchById(WRONG_ACCOUNT_ID)
+
+	recorder := httptest.NewRecorder()
+	collector.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	exposition := recorder.Body.String()
+
+	expectedLines := []string{
+		`rest_client_requests_total{method="GET",route="/v1/organisation/accounts/{id}",status="200"} 2`,
+		`rest_client_requests_total{method="GET",route="/v1/organisation/accounts/{id}",status="404"} 1`,
+		`rest_client_errors_total{method="GET",route="/v1/organisation/accounts/{id}",class="client_error"} 1`,
+		`rest_client_requests_in_flight{method="GET",route="/v1/organisation/accounts/{id}"} 0`,
+		`rest_client_request_duration_seconds_bucket{method="GET",route="/v1/organisation/accounts/{id}",status="200",le="+Inf"} 2`,
+		`rest_client_request_duration_seconds_count{method="GET",route="/v1/organisation/accounts/{id}",status="200"} 2`,
+		`rest_client_request_duration_seconds_count{method="GET",route="/v1/organisation/accounts/{id}",status="404"} 1`,
+		"# TYPE rest_client_request_duration_seconds histogram",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(exposition, line+"\n") {
+			t.Errorf("FAILED: expected exposition to contain %q, got\n%s", line, exposition)
+		}
+	}
+	if strings.Contains(exposition, SINGLE_ACCOUNT_ID) {
+		t.Errorf("FAILED: expected raw account ids to be templated, got\n%s", exposition)
+	}
+}
+
+func TestMetrics_Histogram(t *testing.T) {
+	collector := NewPrometheusCollector([]float64{0.1, 1})
+	collector.RequestStarted("GET", "/accounts")
+	collector.RequestFinished("GET", "/accounts", 200, 500*time.Millisecond, ERROR_CLASS_NONE)
+	collector.RequestRetried("GET", "/accounts")
+	collector.RequestHedged("GET", "/accounts")
+	collector.RequestHedged("GET", "/accounts")
+
+	builder := &strings.Builder{}
+	collector.WriteTo(builder)
+	exposition := builder.String()
+	expectedLines := []string{
+		`rest_client_request_duration_seconds_bucket{method="GET",route="/accounts",status="200",le="0.1"} 0`,
+		`rest_client_request_duration_seconds_bucket{method="GET",route="/accounts",status="200",le="1"} 1`,
+		`rest_client_request_duration_seconds_sum{method="GET",route="/accounts",status="200"} 0.5`,
+		`rest_client_retries_total{method="GET",route="/accounts"} 1`,
+		`rest_client_hedges_total{method="GET",route="/accounts"} 2`,
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(exposition, line+"\n") {
+			t.Errorf("FAILED: expected exposition to contain %q, got\n%s", line, exposition)
+		}
+	}
+}
+
+func TestMetrics_LabelEscaping(t *testing.T) {
+	collector := NewPrometheusCollector(nil)
+	collector.RequestRetried("GET", "/accounts/\"ünicode\"\\\n\t")
+
+	builder := &strings.Builder{}
+	collector.WriteTo(builder)
+	expected := `rest_client_retries_total{method="GET",route="/accounts/\"ünicode\"\\\n` + "\t" + `"} 1`
+	if !strings.Contains(builder.String(), expected+"\n") {
+		t.Errorf("FAILED: expected exposition to contain %q, got\n%s", expected, builder.String())
+	} else {
+		t.Log("SUCCESS: only backslash, double quote and line feed were escaped in label values")
+	}
+}
+
+func TestMetrics_RouteTemplate(t *testing.T) {
+	baseURL := "http://accountapi:8080/v1/organisation/accounts"
+	cases := map[string]string{
+		baseURL:                                "/v1/organisation/accounts",
+		baseURL + "?page[number]=1":            "/v1/organisation/accounts",
+		baseURL + "/" + SINGLE_ACCOUNT_ID:      "/v1/organisation/accounts/{id}",
+		baseURL + "/abc?version=0":             "/v1/organisation/accounts/{id}",
+		"http://other/v1/users/12/" + uuid():   "/v1/users/{id}/{id}",
+		"http://other/v1/organisation/summary": "/v1/organisation/summary",
+	}
+	for raw, expected := range cases {
+		requestURL, _ := url.Parse(raw)
+		if route := routeTemplate(baseURL, requestURL); route != expected {
+			t.Errorf("FAILED: route of %v expected %v, got %v", raw, expected, route)
+		}
+	}
+}
+
+func TestMetrics_ClassifyError(t *testing.T) {
+	cases := []struct {
+		status   int
+		err      error
+		expected string
+	}{
+		{200, nil, ERROR_CLASS_NONE},
+		{404, nil, ERROR_CLASS_CLIENT_ERROR},
+		{400, &ResponseError{Message: "bad"}, ERROR_CLASS_CLIENT_ERROR},
+		{503, nil, ERROR_CLASS_SERVER_ERROR},
+		{200, errors.New("unexpected end of JSON input"), ERROR_CLASS_DECODE},
+		{0, errors.New("connection refused"), ERROR_CLASS_NETWORK},
+	}
+	for _, c := range cases {
+		if class := ClassifyError(c.status, c.err); class != c.expected {
+			t.Errorf("FAILED: class of %v %v expected %q, got %q", c.status, c.err, c.expected, class)
+		}
+	}
+}
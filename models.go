@@ -48,3 +48,71 @@ type AccountAttributes struct {
 	Status                  *string  `json:"status,omitempty"`
 	Switched                *bool    `json:"switched,omitempty"`
 }
+
+// Creates a deep copy of the account data so callers can modify it freely.
+func (accountData *AccountData) clone() *AccountData {
+	if accountData == nil {
+		return nil
+	}
+	copied := *accountData
+	if accountData.Version != nil {
+		version := *accountData.Version
+		copied.Version = &version
+	}
+	if accountData.CreatedOn != nil {
+		createdOn := *accountData.CreatedOn
+		copied.CreatedOn = &createdOn
+	}
+	if accountData.ModifiedOn != nil {
+		modifiedOn := *accountData.ModifiedOn
+		copied.ModifiedOn = &modifiedOn
+	}
+	copied.Attributes = accountData.Attributes.clone()
+	return &copied
+}
+
+// Creates a deep copy of the account attributes.
+func (attributes *AccountAttributes) clone() *AccountAttributes {
+	if attributes == nil {
+		return nil
+	}
+	copied := *attributes
+	copied.AccountClassification = cloneString(attributes.AccountClassification)
+	copied.AccountMatchingOptOut = cloneBool(attributes.AccountMatchingOptOut)
+	copied.Country = cloneString(attributes.Country)
+	copied.JointAccount = cloneBool(attributes.JointAccount)
+	copied.Status = cloneString(attributes.Status)
+	copied.Switched = cloneBool(attributes.Switched)
+	if attributes.AlternativeNames != nil {
+		copied.AlternativeNames = append([]string{}, attributes.AlternativeNames...)
+	}
+	if attributes.Name != nil {
+		copied.Name = append([]string{}, attributes.Name...)
+	}
+	return &copied
+}
+
+// Creates a copy of the links.
+func (links *Links) clone() *Links {
+	if links == nil {
+		return nil
+	}
+	copied := *links
+	return &copied
+}
+
+func cloneString(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
+func cloneBool(value *bool) *bool {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Share of the configured rate the adaptive rate never drops below.
+const RATE_LIMIT_MIN_FACTOR = 0.05
+
+// Share of the configured rate recovered by every successful request after a backoff.
+const RATE_LIMIT_RECOVERY_FACTOR = 0.05
+
+// Token bucket limiting the rate of requests, which slows down when the API answers with 429.
+type RateLimiter struct {
+	mutex       sync.Mutex
+	maxRate     float64
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// Limits the number of requests in flight at the same time.
+type bulkhead chan struct{}
+
+// Creates a rate limiter allowing rate requests per second with bursts of up to burst requests.
+// A rate of zero does not limit requests but still honours Retry-After pauses.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		maxRate: rate,
+		rate:    rate,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		last:    time.Now(),
+	}
+}
+
+// Waits until a request may be sent, or until the context is done.
+func (limiter *RateLimiter) Wait(ctx context.Context) error {
+	limiter.mutex.Lock()
+	now := time.Now()
+	limiter.refill(now)
+	wait := time.Duration(0)
+	if limiter.maxRate > 0 {
+		limiter.tokens--
+	}
+	if limiter.tokens < 0 {
+		wait = time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+	}
+	if pause := limiter.pausedUntil.Sub(now); pause > wait {
+		wait = pause
+	}
+	limiter.mutex.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(wait)) {
+		limiter.cancel()
+		return context.DeadlineExceeded
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		limiter.cancel()
+		return ctx.Err()
+	}
+}
+
+// Returns the current rate in requests per second.
+func (limiter *RateLimiter) Rate() float64 {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	return limiter.rate
+}
+
+// Halves the rate and pauses every request until the Retry-After delay has passed.
+func (limiter *RateLimiter) Backoff(retryAfter time.Duration) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	now := time.Now()
+	limiter.refill(now)
+	limiter.rate = math.Max(limiter.rate/2, limiter.maxRate*RATE_LIMIT_MIN_FACTOR)
+	if until := now.Add(retryAfter); until.After(limiter.pausedUntil) {
+		limiter.pausedUntil = until
+	}
+}
+
+// Raises the rate back towards the configured rate after a successful request.
+func (limiter *RateLimiter) Recover() {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if limiter.rate < limiter.maxRate {
+		limiter.refill(time.Now())
+		limiter.rate = math.Min(limiter.rate+limiter.maxRate*RATE_LIMIT_RECOVERY_FACTOR, limiter.maxRate)
+	}
+}
+
+// Adds the tokens earned since the last refill. Must be called with the mutex held.
+func (limiter *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(limiter.last).Seconds()
+	if elapsed > 0 {
+		limiter.tokens = math.Min(limiter.tokens+elapsed*limiter.rate, limiter.burst)
+		limiter.last = now
+	}
+}
+
+// Returns the token reserved by an abandoned wait.
+func (limiter *RateLimiter) cancel() {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if limiter.maxRate > 0 {
+		limiter.tokens = math.Min(limiter.tokens+1, limiter.burst)
+	}
+}
+
+// Creates a bulkhead allowing up to size requests in flight.
+func newBulkhead(size int) bulkhead {
+	return make(bulkhead, size)
+}
+
+// Waits for a free slot, or until the context is done.
+func (bulkhead bulkhead) acquire(ctx context.Context) error {
+	select {
+	case bulkhead <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Frees a slot taken by acquire.
+func (bulkhead bulkhead) release() {
+	<-bulkhead
+}
+
+// Parses the Retry-After header given in seconds or as http date, defaulting to one second.
+func parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return time.Second
+}
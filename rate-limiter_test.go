@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := NewRateLimiter(50, 1)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("FAILED: Wait returned error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("FAILED: expected 5 requests at 50/s to take at least 80ms, took %v", elapsed)
+	}
+}
+
+func TestRateLimiter_WaitHonoursContext(t *testing.T) {
+	limiter := NewRateLimiter(1, 1)
+	limiter.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := limiter.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FAILED: expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Errorf("FAILED: expected Wait to fail fast when the deadline is too close")
+	}
+}
+
+func TestRateLimiter_BackoffAndRecover(t *testing.T) {
+	limiter := NewRateLimiter(100, 10)
+	limiter.Backoff(30 * time.Millisecond)
+	if limiter.Rate() != 50 {
+		t.Errorf("FAILED: expected rate to be halved to 50, got %v", limiter.Rate())
+	}
+
+	start := time.Now()
+	limiter.Wait(context.Background())
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("FAILED: expected Wait to pause for Retry-After, took %v", elapsed)
+	}
+
+	for i := 0; i < 20; i++ {
+		limiter.Recover()
+	}
+	if limiter.Rate() != 100 {
+		t.Errorf("FAILED: expected rate to recover to 100, got %v", limiter.Rate())
+	}
+}
+
+func TestRateLimiter_ClientBacksOffOn429(t *testing.T) {
+	multiplexer := http.NewServeMux()
+	server := httptest.NewServer(multiplexer)
+	defer server.Close()
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error_message": "too many requests"}`)
+	})
+
+	httpClient := NewHttpClient(&ClientSetting{
+		BaseURL:   server.URL + UNIT_ACCOUNTS_API_BASE,
+		Timeout:   INTEGRATION_TIME_OUT,
+		RateLimit: 100,
+		RateBurst: 10,
+	})
+	accountClient := NewAccountClient(httpClient)
+
+	_, _, res, _ := accountClient.CreateAccount(populateSingleAccountDataUnitTest())
+	if res == nil || res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("FAILED: expected 429 response, got %v", res)
+	}
+	if rate := httpClient.limiter.Rate(); rate != 50 {
+		t.Errorf("FAILED: expected rate to be lowered to 50, got %v", rate)
+	}
+}
+
+func TestRateLimiter_MaxConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	multiplexer := http.NewServeMux()
+	server := httptest.NewServer(multiplexer)
+	defer server.Close()
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE+"/"+SINGLE_ACCOUNT_ID, func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	})
+
+	httpClient := NewHttpClient(&ClientSetting{
+		BaseURL:        server.URL + UNIT_ACCOUNTS_API_BASE,
+		Timeout:        INTEGRATION_TIME_OUT,
+		MaxConcurrency: 2,
+	})
+	accountClient := NewAccountClient(httpClient)
+
+	var group sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			if _, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID); err != nil {
+				t.Errorf("FAILED: FetchById returned error: %v", err)
+			}
+		}()
+	}
+	group.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("FAILED: expected at most 2 requests in flight, got %d", maxInFlight)
+	}
+}
+
+func TestRateLimiter_ParseRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "3")
+	if wait := parseRetryAfter(header); wait != 3*time.Second {
+		t.Errorf("FAILED: expected 3s, got %v", wait)
+	}
+	header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if wait := parseRetryAfter(header); wait < 59*time.Minute {
+		t.Errorf("FAILED: expected about an hour, got %v", wait)
+	}
+	header.Del("Retry-After")
+	if wait := parseRetryAfter(header); wait != time.Second {
+		t.Errorf("FAILED: expected default of 1s, got %v", wait)
+	}
+}
+
+func TestRateLimiter_OpenCircuitFailsWithoutWaiting(t *testing.T) {
+	multiplexer := http.NewServeMux()
+	server := httptest.NewServer(multiplexer)
+	defer server.Close()
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE+"/"+SINGLE_ACCOUNT_ID, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error_message": "unavailable"}`)
+	})
+
+	httpClient := NewHttpClient(&ClientSetting{
+		BaseURL:        server.URL + UNIT_ACCOUNTS_API_BASE,
+		Timeout:        INTEGRATION_TIME_OUT,
+		RateLimit:      1,
+		RateBurst:      1,
+		CircuitBreaker: &CircuitBreakerSetting{FailureRatio: 0.5, MinRequests: 1, CoolDown: time.Minute},
+	})
+	accountClient := NewAccountClient(httpClient)
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("FAILED: expected ErrCircuitOpen, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("FAILED: expected an open circuit to fail without waiting for the rate limiter, took %v", elapsed)
+	} else {
+		t.Logf("SUCCESS: open circuit failed fast in %v", elapsed)
+	}
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const REDACTED_VALUE = "[REDACTED]"
+
+// Headers masked by every redactor in addition to the configured ones.
+var REDACTED_HEADERS_DEFAULT = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+}
+
+// JSON keys of account attributes holding personal data.
+var REDACTED_JSON_KEYS = []string{
+	"account_number",
+	"alternative_names",
+	"iban",
+	"name",
+	"secondary_identification",
+}
+
+var (
+	redactedJsonValuePattern = regexp.MustCompile(`"(` + strings.Join(REDACTED_JSON_KEYS, "|") + `)"\s*:\s*("(?:[^"\\]|\\.)*"|\[[^\]]*\])`)
+	redactedIbanPattern      = regexp.MustCompile(`\b[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}\b`)
+	redactedTokenPattern     = regexp.MustCompile(`[\w-]+`)
+	redactedNumberPattern    = regexp.MustCompile(`^[0-9]{7,}$`)
+)
+
+// Masks personal data in account payloads, headers and free text before the client emits them.
+type Redactor struct {
+	headers map[string]bool
+	keys    map[string]bool
+}
+
+type redactingLogger struct {
+	logger   Logger
+	redactor *Redactor
+}
+
+// Creates a redactor masking the default sensitive headers and the given header names.
+func NewRedactor(headers ...string) *Redactor {
+	redactor := &Redactor{
+		headers: map[string]bool{},
+		keys:    map[string]bool{},
+	}
+	for _, header := range append(REDACTED_HEADERS_DEFAULT, headers...) {
+		redactor.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	for _, key := range REDACTED_JSON_KEYS {
+		redactor.keys[key] = true
+	}
+	return redactor
+}
+
+// Returns a copy of the headers with sensitive values masked.
+func (redactor *Redactor) Header(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for key, values := range header {
+		if redactor.headers[http.CanonicalHeaderKey(key)] {
+			redacted[key] = []string{REDACTED_VALUE}
+			continue
+		}
+		redacted[key] = append([]string{}, values...)
+	}
+	return redacted
+}
+
+// Returns the JSON document with personal data fields masked at any depth.
+// Documents which cannot be parsed are redacted as free text.
+func (redactor *Redactor) JSON(body []byte) []byte {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return []byte(redactor.String(string(body)))
+	}
+	redacted, err := json.Marshal(redactor.redactValue(document))
+	if err != nil {
+		return []byte(REDACTED_VALUE)
+	}
+	return redacted
+}
+
+// Returns the text with embedded personal data fields, IBANs and long digit sequences masked.
+func (redactor *Redactor) String(text string) string {
+	text = redactedJsonValuePattern.ReplaceAllString(text, `"$1":"`+REDACTED_VALUE+`"`)
+	text = redactedIbanPattern.ReplaceAllString(text, REDACTED_VALUE)
+	// Only whole tokens are masked, so digit groups of UUIDs and request IDs are left intact.
+	return redactedTokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		if redactedNumberPattern.MatchString(token) {
+			return REDACTED_VALUE
+		}
+		return token
+	})
+}
+
+// Returns the log field with its value redacted according to its type.
+func (redactor *Redactor) Field(field LogField) LogField {
+	switch value := field.Value.(type) {
+	case string:
+		field.Value = redactor.String(value)
+	case []byte:
+		field.Value = string(redactor.JSON(value))
+	case http.Header:
+		field.Value = redactor.Header(value)
+	case *AccountData:
+		field.Value = value.Redacted()
+	case error:
+		field.Value = redactor.String(value.Error())
+	case fmt.Stringer:
+		field.Value = redactor.String(value.String())
+	}
+	return field
+}
+
+func (redactor *Redactor) redactValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			if redactor.keys[key] && nested != nil {
+				typed[key] = REDACTED_VALUE
+				continue
+			}
+			typed[key] = redactor.redactValue(nested)
+		}
+	case []interface{}:
+		for i, nested := range typed {
+			typed[i] = redactor.redactValue(nested)
+		}
+	}
+	return value
+}
+
+// Wraps a logger so every field passes through the redactor before it is emitted.
+func newRedactingLogger(logger Logger, redactor *Redactor) Logger {
+	return &redactingLogger{
+		logger:   logger,
+		redactor: redactor,
+	}
+}
+
+func (redactingLogger *redactingLogger) Log(level LogLevel, message string, fields ...LogField) {
+	redacted := make([]LogField, len(fields))
+	for i, field := range fields {
+		redacted[i] = redactingLogger.redactor.Field(field)
+	}
+	redactingLogger.logger.Log(level, redactingLogger.redactor.String(message), redacted...)
+}
+
+// Returns a copy of the account data with personal data masked, safe for `%v` printing.
+func (accountData *AccountData) Redacted() *AccountData {
+	redacted := accountData.clone()
+	if redacted == nil || redacted.Attributes == nil {
+		return redacted
+	}
+	attributes := redacted.Attributes
+	if attributes.AccountNumber != "" {
+		attributes.AccountNumber = REDACTED_VALUE
+	}
+	if attributes.Iban != "" {
+		attributes.Iban = REDACTED_VALUE
+	}
+	if attributes.SecondaryIdentification != "" {
+		attributes.SecondaryIdentification = REDACTED_VALUE
+	}
+	for i := range attributes.Name {
+		attributes.Name[i] = REDACTED_VALUE
+	}
+	for i := range attributes.AlternativeNames {
+		attributes.AlternativeNames[i] = REDACTED_VALUE
+	}
+	return redacted
+}
+
+// Formats the attributes as JSON with personal data masked, so printing account data never leaks it.
+func (attributes *AccountAttributes) String() string {
+	if attributes == nil {
+		return "<nil>"
+	}
+	redacted := (&AccountData{Attributes: attributes}).Redacted().Attributes
+	encoded, err := json.Marshal(redacted)
+	if err != nil {
+		return REDACTED_VALUE
+	}
+	return string(encoded)
+}
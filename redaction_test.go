@@ -0,0 +1,121 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedaction_AccountDataRedacted(t *testing.T) {
+	accountData := populateSingleAccountDataUnitTest()
+	accountData.Attributes.AlternativeNames = []string{"Minul"}
+
+	redacted := accountData.Redacted()
+	attributes := redacted.Attributes
+	if attributes.Iban != REDACTED_VALUE || attributes.AccountNumber != REDACTED_VALUE ||
+		attributes.SecondaryIdentification != REDACTED_VALUE ||
+		attributes.Name[0] != REDACTED_VALUE || attributes.AlternativeNames[0] != REDACTED_VALUE {
+		t.Errorf("FAILED: expected personal data to be redacted, got %v", attributes)
+	}
+	if accountData.Attributes.Iban == REDACTED_VALUE || accountData.Attributes.Name[0] == REDACTED_VALUE {
+		t.Errorf("FAILED: Redacted modified the original account data")
+	}
+	if attributes.BankID != accountData.Attributes.BankID {
+		t.Errorf("FAILED: expected bank id %v to be kept, got %v", accountData.Attributes.BankID, attributes.BankID)
+	}
+
+	printed := fmt.Sprintf("%v %+v", accountData, redacted)
+	for _, secret := range []string{"Shah Minul Amin", "GB43NWBK40030212764896", "10000001"} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("FAILED: printed account data leaked %q: %s", secret, printed)
+		}
+	}
+}
+
+func TestRedaction_JSON(t *testing.T) {
+	redactor := NewRedactor()
+	redacted := string(redactor.JSON([]byte(SINGLE_ACCOUNT_MOCK_RESPONSE)))
+
+	for _, secret := range []string{"Shah Minul Amin", "GB43NWBK40030212764896", "10000001"} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("FAILED: redacted JSON leaked %q: %s", secret, redacted)
+		}
+	}
+	if !strings.Contains(redacted, SINGLE_ACCOUNT_ID) {
+		t.Errorf("FAILED: expected account id to be kept, got %s", redacted)
+	}
+}
+
+func TestRedaction_StringAndHeader(t *testing.T) {
+	redactor := NewRedactor("X-Api-Key")
+
+	text := redactor.String(`account GB43NWBK40030212764896 number 10000001 {"name":["Shah Minul Amin"]}`)
+	expected := `account [REDACTED] number [REDACTED] {"name":"[REDACTED]"}`
+	if text != expected {
+		t.Errorf("FAILED: redacted string expected %q, got %q", expected, text)
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret")
+	header.Set("X-Api-Key", "secret")
+	header.Set("Content-Type", "application/json")
+	redacted := redactor.Header(header)
+	if redacted.Get("Authorization") != REDACTED_VALUE || redacted.Get("X-Api-Key") != REDACTED_VALUE {
+		t.Errorf("FAILED: expected sensitive headers to be redacted, got %v", redacted)
+	}
+	if redacted.Get("Content-Type") != "application/json" || header.Get("X-Api-Key") != "secret" {
+		t.Errorf("FAILED: expected other headers and original to be kept, got %v %v", redacted, header)
+	}
+}
+
+func TestRedaction_StringKeepsIdentifiers(t *testing.T) {
+	redactor := NewRedactor()
+	identifiers := "account 12345678-1234-4234-8234-123456789012 failed (request_id=00000000-0000-4000-8000-000000000001, code=12345678a)"
+	if redacted := redactor.String(identifiers); redacted != identifiers {
+		t.Errorf("FAILED: expected UUIDs and request IDs to survive, got %q", redacted)
+	}
+	numbers := "12345678,87654321 12345678"
+	expected := strings.Join([]string{REDACTED_VALUE + "," + REDACTED_VALUE, REDACTED_VALUE}, " ")
+	if redacted := redactor.String(numbers); redacted != expected {
+		t.Errorf("FAILED: expected adjacent numbers to be masked, got %q", redacted)
+	} else {
+		t.Logf("SUCCESS: adjacent numbers masked in one pass: %q", redacted)
+	}
+}
+
+func TestRedaction_ClientLogsAndErrors(t *testing.T) {
+	logger := &recordingLogger{}
+	multiplexer := http.NewServeMux()
+	server := httptest.NewServer(multiplexer)
+	defer server.Close()
+	httpClient := NewHttpClient(&ClientSetting{
+		BaseURL:       server.URL + UNIT_ACCOUNTS_API_BASE,
+		Timeout:       INTEGRATION_TIME_OUT,
+		Logger:        logger,
+		RedactHeaders: []string{"X-Api-Key"},
+	})
+	accountClient := NewAccountClient(httpClient)
+
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error_message": "iban GB43NWBK40030212764896 is invalid"}`)
+	})
+
+	_, _, _, err := accountClient.CreateAccount(populateSingleAccountDataUnitTest())
+	if err == nil || strings.Contains(err.Error(), "GB43NWBK40030212764896") {
+		t.Errorf("FAILED: expected redacted error, got %v", err)
+	}
+
+	header := http.Header{}
+	header.Set("X-Api-Key", "secret")
+	httpClient.logger.Log(LOG_LEVEL_DEBUG, "dump", Field("headers", header), Field("account", populateSingleAccountDataUnitTest()))
+	event := logger.find("dump")
+	if fmt.Sprint(event.fields["headers"]) != fmt.Sprint(http.Header{"X-Api-Key": {REDACTED_VALUE}}) {
+		t.Errorf("FAILED: expected redacted header field, got %v", event.fields["headers"])
+	}
+	if strings.Contains(fmt.Sprintf("%+v", event.fields["account"]), "Shah Minul Amin") {
+		t.Errorf("FAILED: expected redacted account field, got %+v", event.fields["account"])
+	}
+}
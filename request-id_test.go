@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID_GeneratedWhenMissing(t *testing.T) {
+	accountClient, multiplexer, closeServer := prepareTestAccountClient()
+	defer closeServer()
+
+	var received string
+	muxUrl := UNIT_ACCOUNTS_API_BASE + "/" + SINGLE_ACCOUNT_ID
+	multiplexer.HandleFunc(muxUrl, func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(REQUEST_ID_HEADER)
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	})
+
+	_, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if err != nil {
+		t.Fatalf("FAILED: FetchById returned error: %v", err)
+	}
+	if len(received) != 36 {
+		t.Errorf("FAILED: expected generated request id, got %q", received)
+	}
+}
+
+func TestRequestID_FromContextAndInError(t *testing.T) {
+	accountClient, multiplexer, closeServer := prepareTestAccountClient()
+	defer closeServer()
+
+	var received string
+	muxUrl := UNIT_ACCOUNTS_API_BASE + "/" + WRONG_ACCOUNT_ID
+	multiplexer.HandleFunc(muxUrl, func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(REQUEST_ID_HEADER)
+		w.Header().Set(REQUEST_ID_HEADER, "server-id")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, WRONG_ACCOUNT_MOCK_RESPONSE)
+	})
+
+	ctx := ContextWithRequestID(context.Background(), "client-id")
+	_, _, _, err := accountClient.FetchByIdWithContext(ctx, WRONG_ACCOUNT_ID)
+	if received != "client-id" {
+		t.Errorf("FAILED: expected request id %q to be sent, got %q", "client-id", received)
+	}
+
+	var responseError *ResponseError
+	if !errors.As(err, &responseError) {
+		t.Fatalf("FAILED: expected ResponseError, got %T %v", err, err)
+	}
+	if responseError.RequestID != "client-id" || responseError.ServerRequestID != "server-id" ||
+		responseError.StatusCode != http.StatusNotFound {
+		t.Errorf("FAILED: unexpected response error %+v", responseError)
+	}
+	expected := "Error occurred (request_id=client-id, server_request_id=server-id)"
+	if err.Error() != expected {
+		t.Errorf("FAILED: error message expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestRequestID_TransportErrorAndLogs(t *testing.T) {
+	logger := &recordingLogger{}
+	httpClient := NewHttpClient(&ClientSetting{BaseURL: "http://127.0.0.1:1", Timeout: 1000, Logger: logger})
+	accountClient := NewAccountClient(httpClient)
+
+	ctx := ContextWithRequestID(context.Background(), "client-id")
+	_, _, _, err := accountClient.FetchByIdWithContext(ctx, SINGLE_ACCOUNT_ID)
+	if err == nil || !strings.Contains(err.Error(), "request_id=client-id") {
+		t.Errorf("FAILED: expected transport error with request id, got %v", err)
+	}
+	event := logger.find("request failed")
+	if event == nil || event.fields["request_id"] != "client-id" {
+		t.Errorf("FAILED: expected request failed event with request id, got %+v", logger.events)
+	}
+}
+
+func TestRequestID_Middleware(t *testing.T) {
+	accountClient, multiplexer, closeServer := prepareTestAccountClient()
+	defer closeServer()
+
+	var received string
+	muxUrl := UNIT_ACCOUNTS_API_BASE + "/" + SINGLE_ACCOUNT_ID
+	multiplexer.HandleFunc(muxUrl, func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(REQUEST_ID_HEADER)
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	})
+
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accountClient.FetchByIdWithContext(r.Context(), SINGLE_ACCOUNT_ID)
+	}))
+	inbound := httptest.NewRequest("GET", "/checkout", nil)
+	inbound.Header.Set(REQUEST_ID_HEADER, "inbound-id")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, inbound)
+
+	if received != "inbound-id" {
+		t.Errorf("FAILED: expected inbound request id to flow through, got %q", received)
+	}
+	if recorder.Header().Get(REQUEST_ID_HEADER) != "inbound-id" {
+		t.Errorf("FAILED: expected request id on inbound response, got %q", recorder.Header().Get(REQUEST_ID_HEADER))
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Configures a single request sent with Do.
+type RequestOption func(options *requestOptions)
+
+type requestOptions struct {
+	header       http.Header
+	query        url.Values
+	payload      interface{}
+	rawBody      []byte
+	contentType  string
+	timeout      time.Duration
+	responseData interface{}
+	linkData     interface{}
+}
+
+type requestTimeoutKey struct{}
+
+// Sets a request header, replacing any value set by the client.
+func WithHeader(key, value string) RequestOption {
+	return func(options *requestOptions) {
+		options.header.Set(key, value)
+	}
+}
+
+// Adds a query parameter to the url.
+func WithQuery(key, value string) RequestOption {
+	return func(options *requestOptions) {
+		options.query.Add(key, value)
+	}
+}
+
+// Sends the payload wrapped in the data envelope of the API as JSON.
+func WithPayload(payload interface{}) RequestOption {
+	return func(options *requestOptions) {
+		options.payload = payload
+		options.rawBody = nil
+	}
+}
+
+// Sends the body as is with the content type.
+func WithRawBody(body []byte, contentType string) RequestOption {
+	return func(options *requestOptions) {
+		options.rawBody = body
+		options.contentType = contentType
+		options.payload = nil
+	}
+}
+
+// Overrides the client timeout for the request, which may be longer or shorter than the client timeout.
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(options *requestOptions) {
+		options.timeout = timeout
+	}
+}
+
+// Decodes the data and links of the response envelope into response data and link data.
+// Link data may be nil when the links are not needed.
+func WithResponse(responseData interface{}, linkData interface{}) RequestOption {
+	return func(options *requestOptions) {
+		options.responseData = responseData
+		options.linkData = linkData
+	}
+}
+
+// Sends a request with any http method to the url, configured by the request options. The body of the
+// returned response can be read again, so responses outside the data envelope can be decoded by the caller.
+// Returns http response.
+func (httpClient *HttpClient) Do(ctx context.Context, method string, url string, options ...RequestOption) (*http.Response, error) {
+	requestOptions := newRequestOptions()
+	for _, option := range options {
+		option(requestOptions)
+	}
+
+	var httpRequest *http.Request
+	var err error
+	if requestOptions.rawBody != nil {
+		httpRequest, err = httpClient.newRawHttpRequest(method, url, requestOptions.rawBody, requestOptions.contentType)
+	} else {
+		httpRequest, err = httpClient.newHttpRequest(method, url, requestOptions.payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(requestOptions.query) > 0 {
+		query := httpRequest.URL.Query()
+		for key, values := range requestOptions.query {
+			for _, value := range values {
+				query.Add(key, value)
+			}
+		}
+		httpRequest.URL.RawQuery = query.Encode()
+	}
+	for key, values := range requestOptions.header {
+		httpRequest.Header[key] = values
+	}
+	if requestOptions.timeout > 0 {
+		ctx = context.WithValue(ctx, requestTimeoutKey{}, requestOptions.timeout)
+	}
+
+	linkData := requestOptions.linkData
+	if requestOptions.responseData != nil && linkData == nil {
+		linkData = new(Links)
+	}
+	return httpClient.perform(ctx, httpRequest, requestOptions.responseData, linkData)
+}
+
+func newRequestOptions() *requestOptions {
+	return &requestOptions{header: http.Header{}, query: url.Values{}}
+}
+
+// Returns the timeout of a request, preferring a timeout set with WithTimeout over the client timeout.
+func (httpClient *HttpClient) requestTimeout(ctx context.Context) time.Duration {
+	if timeout, ok := ctx.Value(requestTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return httpClient.timeout
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type capturedRequest struct {
+	method string
+	url    string
+	header http.Header
+	body   string
+}
+
+func prepareTestRequestOptionClient(timeout int, handler http.HandlerFunc) (*HttpClient, chan capturedRequest, func()) {
+	captured := make(chan capturedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		captured <- capturedRequest{method: r.Method, url: r.URL.String(), header: r.Header, body: string(body)}
+		handler(w, r)
+	}))
+	httpClient := NewHttpClient(&ClientSetting{BaseURL: server.URL + UNIT_ACCOUNTS_API_BASE, Timeout: timeout})
+	return httpClient, captured, server.Close
+}
+
+func accountResponseHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+}
+
+func TestRequestOption_PutAndPatch(t *testing.T) {
+	httpClient, captured, closeServer := prepareTestRequestOptionClient(INTEGRATION_TIME_OUT, accountResponseHandler)
+	defer closeServer()
+	url := httpClient.BaseURL + "/" + SINGLE_ACCOUNT_ID
+
+	for _, send := range []func(string, interface{}, interface{}, interface{}) (*http.Response, error){httpClient.Put, httpClient.Patch} {
+		account := new(AccountData)
+		res, err := send(url, populateSingleAccountDataUnitTest(), account, new(Links))
+		request := <-captured
+		if err != nil || res.StatusCode != http.StatusOK || account.ID != SINGLE_ACCOUNT_ID {
+			t.Errorf("FAILED: expected account in response to %s, got %v %v", request.method, account, err)
+		}
+		if !strings.HasPrefix(request.body, `{"data":{"attributes"`) || request.header.Get("Content-Type") != "application/json" {
+			t.Errorf("FAILED: expected an enveloped JSON payload for %s, got %s", request.method, request.body)
+		}
+	}
+	t.Log("SUCCESS: PUT and PATCH sent the payload and decoded the response")
+}
+
+func TestRequestOption_DoWithOptions(t *testing.T) {
+	httpClient, captured, closeServer := prepareTestRequestOptionClient(INTEGRATION_TIME_OUT, accountResponseHandler)
+	defer closeServer()
+
+	account := new(AccountData)
+	res, err := httpClient.Do(context.Background(), "PATCH", httpClient.BaseURL+"/"+SINGLE_ACCOUNT_ID+"?version=0",
+		WithHeader("If-Match", `"v1"`),
+		WithQuery("filter[status]", "confirmed"),
+		WithPayload(map[string]string{"type": "accounts"}),
+		WithResponse(account, nil),
+	)
+	request := <-captured
+	if err != nil || account.ID != SINGLE_ACCOUNT_ID {
+		t.Fatalf("FAILED: expected Do to decode the account, got %v %v", account, err)
+	}
+	if request.method != "PATCH" || request.header.Get("If-Match") != `"v1"` {
+		t.Errorf("FAILED: expected PATCH with If-Match header, got %s %v", request.method, request.header)
+	}
+	if !strings.Contains(request.url, "version=0") || !strings.Contains(request.url, "filter%5Bstatus%5D=confirmed") {
+		t.Errorf("FAILED: expected both query parameters, got %s", request.url)
+	}
+	if request.body != `{"data":{"type":"accounts"}}` {
+		t.Errorf("FAILED: expected enveloped payload, got %s", request.body)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	if !strings.Contains(string(body), SINGLE_ACCOUNT_ID) {
+		t.Errorf("FAILED: expected the response body to be readable, got %q", body)
+	}
+}
+
+func TestRequestOption_DoWithRawBody(t *testing.T) {
+	httpClient, captured, closeServer := prepareTestRequestOptionClient(INTEGRATION_TIME_OUT, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	defer closeServer()
+
+	res, err := httpClient.Do(context.Background(), "POST", httpClient.BaseURL+"/actions",
+		WithRawBody([]byte("<xml/>"), "application/xml"))
+	request := <-captured
+	if err != nil || res.StatusCode != http.StatusAccepted {
+		t.Fatalf("FAILED: expected 202 response, got %v %v", res, err)
+	}
+	if request.body != "<xml/>" || request.header.Get("Content-Type") != "application/xml" {
+		t.Errorf("FAILED: expected raw body with its content type, got %q %v", request.body, request.header)
+	}
+}
+
+func TestRequestOption_TimeoutOverride(t *testing.T) {
+	httpClient, captured, closeServer := prepareTestRequestOptionClient(20, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(60 * time.Millisecond)
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	})
+	defer closeServer()
+	url := httpClient.BaseURL + "/" + SINGLE_ACCOUNT_ID
+
+	if _, err := httpClient.Do(context.Background(), "GET", url); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FAILED: expected the client timeout to apply, got %v", err)
+	}
+	<-captured
+	if _, err := httpClient.Do(context.Background(), "GET", url, WithTimeout(time.Second)); err != nil {
+		t.Errorf("FAILED: expected a longer request timeout to override the client timeout, got %v", err)
+	}
+	<-captured
+}
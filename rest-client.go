@@ -5,19 +5,36 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
 type ResponseError struct {
-	Message string `json:"error_message"`
+	Message         string `json:"error_message"`
+	StatusCode      int    `json:"-"`
+	RequestID       string `json:"-"`
+	ServerRequestID string `json:"-"`
 }
 
 type HttpClient struct {
-	client  *http.Client
-	BaseURL string
+	client    *http.Client
+	logger    Logger
+	redactor  *Redactor
+	metrics   MetricsCollector
+	tracer    Tracer
+	breakers  *circuitBreakerGroup
+	limiter   *RateLimiter
+	bulkhead  bulkhead
+	hedger    *hedger
+	endpoints *endpointPool
+	maxSize   int64
+	compress  int
+	timeout   time.Duration
+	BaseURL   string
 }
 
 type RestClient struct {
@@ -25,8 +42,29 @@ type RestClient struct {
 }
 
 type ClientSetting struct {
-	BaseURL string
-	Timeout int
+	BaseURL        string
+	Timeout        int
+	Logger         Logger
+	RedactHeaders  []string
+	Metrics        MetricsCollector
+	Tracer         Tracer
+	CircuitBreaker *CircuitBreakerSetting
+	RateLimit      float64
+	RateBurst      int
+	MaxConcurrency int
+	Hedging        *HedgingPolicy
+	// Base URLs of the same API in several regions. BaseURL defaults to the first of them
+	// and requests built from BaseURL fail over to the others.
+	BaseURLs          []string
+	EndpointSelection EndpointSelection
+	EjectAfter        int
+	ProbeInterval     time.Duration
+	// Maximum number of bytes read from a decompressed response body, unlimited when zero.
+	MaxResponseSize int64
+	// Request bodies larger than this number of bytes are sent gzip compressed, never when zero.
+	CompressRequestsOver int
+	// Transport used to send requests, http.DefaultTransport when nil.
+	Transport http.RoundTripper
 }
 
 var CLIENT_SETTING_DEFAULT = &ClientSetting{
@@ -39,58 +77,178 @@ func NewHttpClient(setting *ClientSetting) *HttpClient {
 	if setting == nil {
 		setting = CLIENT_SETTING_DEFAULT
 	}
-	return &HttpClient{
+	redactor := NewRedactor(setting.RedactHeaders...)
+	logger := NewNoopLogger()
+	if setting.Logger != nil {
+		logger = newRedactingLogger(setting.Logger, redactor)
+	}
+	metrics := setting.Metrics
+	if metrics == nil {
+		metrics = NewNoopMetricsCollector()
+	}
+	tracer := setting.Tracer
+	if tracer == nil {
+		tracer = NewNoopTracer()
+	}
+	var breakers *circuitBreakerGroup
+	if setting.CircuitBreaker != nil {
+		breakers = newCircuitBreakerGroup(setting.CircuitBreaker, logger)
+	}
+	var limiter *RateLimiter
+	if setting.RateLimit > 0 {
+		limiter = NewRateLimiter(setting.RateLimit, setting.RateBurst)
+	}
+	var concurrency bulkhead
+	if setting.MaxConcurrency > 0 {
+		concurrency = newBulkhead(setting.MaxConcurrency)
+	}
+	var requestHedger *hedger
+	if setting.Hedging != nil {
+		requestHedger = newHedger(setting.Hedging)
+	}
+	baseURL := setting.BaseURL
+	if baseURL == "" && len(setting.BaseURLs) > 0 {
+		baseURL = setting.BaseURLs[0]
+	}
+	httpClient := &HttpClient{
 		client: &http.Client{
-			Timeout: time.Duration(setting.Timeout) * time.Millisecond,
+			Transport: setting.Transport,
 		},
-		BaseURL: setting.BaseURL,
+		logger:   logger,
+		redactor: redactor,
+		metrics:  metrics,
+		tracer:   tracer,
+		breakers: breakers,
+		limiter:  limiter,
+		bulkhead: concurrency,
+		hedger:   requestHedger,
+		maxSize:  setting.MaxResponseSize,
+		compress: setting.CompressRequestsOver,
+		timeout:  time.Duration(setting.Timeout) * time.Millisecond,
+		BaseURL:  baseURL,
 	}
+	if len(setting.BaseURLs) > 1 {
+		httpClient.endpoints = newEndpointPool(setting.BaseURLs, setting, httpClient.probeEndpoint, logger)
+	}
+	return httpClient
 }
 
 // Http GET method implementation using url and payload, also takes response data and link data interfaces.
 // Returns http response.
 func (httpClient *HttpClient) Get(url string, payload interface{}, responseData interface{}, linkData interface{}) (*http.Response, error) {
+	return httpClient.GetWithContext(context.Background(), url, payload, responseData, linkData)
+}
+
+// Http GET method implementation using context, url and payload, also takes response data and link data interfaces.
+// Returns http response.
+func (httpClient *HttpClient) GetWithContext(ctx context.Context, url string, payload interface{}, responseData interface{}, linkData interface{}) (*http.Response, error) {
 	request, err := httpClient.newHttpRequest("GET", url, payload)
 	if err != nil {
 		return nil, err
 	}
 
-	return httpClient.perform(context.Background(), request, responseData, linkData)
+	return httpClient.perform(ctx, request, responseData, linkData)
 }
 
 // Http POST method implementation using url and payload, also takes response data and link data interfaces.
 // Returns http response.
 func (httpClient *HttpClient) Post(url string, payload interface{}, responseData interface{}, linkData interface{}) (*http.Response, error) {
+	return httpClient.PostWithContext(context.Background(), url, payload, responseData, linkData)
+}
+
+// Http POST method implementation using context, url and payload, also takes response data and link data interfaces.
+// Returns http response.
+func (httpClient *HttpClient) PostWithContext(ctx context.Context, url string, payload interface{}, responseData interface{}, linkData interface{}) (*http.Response, error) {
 	request, err := httpClient.newHttpRequest("POST", url, payload)
 	if err != nil {
 		return nil, err
 	}
 
-	return httpClient.perform(context.Background(), request, responseData, linkData)
+	return httpClient.perform(ctx, request, responseData, linkData)
+}
+
+// Http PUT method implementation using url and payload, also takes response data and link data interfaces.
+// Returns http response.
+func (httpClient *HttpClient) Put(url string, payload interface{}, responseData interface{}, linkData interface{}) (*http.Response, error) {
+	return httpClient.PutWithContext(context.Background(), url, payload, responseData, linkData)
+}
+
+// Http PUT method implementation using context, url and payload, also takes response data and link data interfaces.
+// Returns http response.
+func (httpClient *HttpClient) PutWithContext(ctx context.Context, url string, payload interface{}, responseData interface{}, linkData interface{}) (*http.Response, error) {
+	request, err := httpClient.newHttpRequest("PUT", url, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpClient.perform(ctx, request, responseData, linkData)
+}
+
+// Http PATCH method implementation using url and payload, also takes response data and link data interfaces.
+// Returns http response.
+func (httpClient *HttpClient) Patch(url string, payload interface{}, responseData interface{}, linkData interface{}) (*http.Response, error) {
+	return httpClient.PatchWithContext(context.Background(), url, payload, responseData, linkData)
+}
+
+// Http PATCH method implementation using context, url and payload, also takes response data and link data interfaces.
+// Returns http response.
+func (httpClient *HttpClient) PatchWithContext(ctx context.Context, url string, payload interface{}, responseData interface{}, linkData interface{}) (*http.Response, error) {
+	request, err := httpClient.newHttpRequest("PATCH", url, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpClient.perform(ctx, request, responseData, linkData)
 }
 
 // Http DELETE method implementation using url.
 // Returns http response.
 func (httpClient *HttpClient) Delete(url string) (*http.Response, error) {
+	return httpClient.DeleteWithContext(context.Background(), url)
+}
+
+// Http DELETE method implementation using context and url.
+// Returns http response.
+func (httpClient *HttpClient) DeleteWithContext(ctx context.Context, url string) (*http.Response, error) {
 	request, err := httpClient.newHttpRequest("DELETE", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return httpClient.perform(context.Background(), request, nil, nil)
+	return httpClient.perform(ctx, request, nil, nil)
 }
 
 // Creates a new http request from http method name, url and payload body.
 // Returns http request.
 func (httpClient *HttpClient) newHttpRequest(method, url string, bodyType interface{}) (*http.Request, error) {
-	var payloadBuffer io.Reader
+	var body []byte
 	if bodyType != nil {
 		bodyData := ResponseBody{Data: bodyType}
 		bodyJson, err := json.Marshal(bodyData)
 		if err != nil {
 			return nil, err
 		}
-		payloadBuffer = bytes.NewBuffer(bodyJson)
+		body = bodyJson
+	}
+
+	return httpClient.newRawHttpRequest(method, url, body, "application/json")
+}
+
+// Creates a new http request from http method name, url, raw body and its content type.
+// Bodies larger than the configured size are gzip compressed.
+// Returns http request.
+func (httpClient *HttpClient) newRawHttpRequest(method, url string, body []byte, contentType string) (*http.Request, error) {
+	var payloadBuffer io.Reader
+	compressed := false
+	if body != nil {
+		if httpClient.compress > 0 && len(body) > httpClient.compress {
+			var err error
+			if body, err = compressBody(body); err != nil {
+				return nil, err
+			}
+			compressed = true
+		}
+		payloadBuffer = bytes.NewBuffer(body)
 	}
 
 	request, err := http.NewRequest(method, url, payloadBuffer)
@@ -98,44 +256,244 @@ func (httpClient *HttpClient) newHttpRequest(method, url string, bodyType interf
 		return nil, err
 	}
 
-	request.Header.Set("Content-Type", "application/json")
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if compressed {
+		request.Header.Set(CONTENT_ENCODING_HEADER, GZIP_ENCODING)
+	}
 	return request, nil
 }
 
 // Performs a http request using context and http request, also takes response data and link data interfaces.
-// Returns http response.
+// The request is bounded by the client timeout unless overridden with WithTimeout.
+// Returns http response, whose body holds the bytes read.
 func (httpClient *HttpClient) perform(ctx context.Context, httpRequest *http.Request, responseData interface{}, linkData interface{}) (*http.Response, error) {
+	requestID := httpRequest.Header.Get(REQUEST_ID_HEADER)
+	if requestID == "" {
+		requestID = RequestIDFromContext(ctx)
+	}
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	httpRequest.Header.Set(REQUEST_ID_HEADER, requestID)
+	if httpRequest.Header.Get(ACCEPT_ENCODING_HEADER) == "" {
+		httpRequest.Header.Set(ACCEPT_ENCODING_HEADER, GZIP_ENCODING)
+	}
+
+	if timeout := httpClient.requestTimeout(ctx); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	method := httpRequest.Method
+	route := routeTemplate(httpClient.BaseURL, httpRequest.URL)
+	ctx, span := httpClient.tracer.StartSpan(ContextWithRequestID(ctx, requestID), "HTTP "+method)
+	defer span.End()
+	span.SetAttribute("http.method", method)
+	span.SetAttribute("http.route", route)
+	span.SetAttribute("request_id", requestID)
 	httpRequest = httpRequest.WithContext(ctx)
-	httpResponse, err := httpClient.client.Do(httpRequest)
+
+	httpClient.metrics.RequestStarted(method, route)
+	start := time.Now()
+
+	httpResponse, responseBytes, err := httpClient.execute(httpRequest, route)
+	status := 0
+	if httpResponse != nil {
+		status = httpResponse.StatusCode
+	}
+	if err == nil {
+		err = httpClient.decodeResponseBody(httpResponse, responseBytes, responseData, linkData)
+	}
+
+	httpClient.metrics.RequestFinished(method, route, status, time.Since(start), ClassifyError(status, err))
+	span.SetStatus(status)
 	if err != nil {
+		span.RecordError(err)
+	}
+	if err != nil && !isResponseError(err) {
 		return nil, err
 	}
+	if httpResponse != nil && responseBytes != nil {
+		httpResponse.Body = ioutil.NopCloser(bytes.NewReader(responseBytes))
+	}
+	return httpResponse, err
+}
+
+// Executes the http request, failing over between base URLs when several are configured.
+// Returns http response and response body.
+func (httpClient *HttpClient) execute(httpRequest *http.Request, route string) (*http.Response, []byte, error) {
+	attempts := new(int32)
+	if httpClient.endpoints != nil {
+		return httpClient.failover(httpRequest, route, attempts)
+	}
+	return httpClient.executeOnce(httpRequest, route, attempts)
+}
+
+// Executes the http request against a single endpoint, hedging idempotent GET requests when
+// a hedging policy is configured. Streamed responses are never hedged, as the body would be
+// consumed twice. Every execution after the first attempt of the request is counted as retry.
+// Returns http response and response body.
+func (httpClient *HttpClient) executeOnce(httpRequest *http.Request, route string, attempts *int32) (*http.Response, []byte, error) {
+	if atomic.LoadInt32(attempts) > 0 {
+		httpClient.metrics.RequestRetried(httpRequest.Method, route)
+	}
+	streaming := bodyConsumerFromContext(httpRequest.Context()) != nil
+	if httpClient.hedger != nil && httpRequest.Method == http.MethodGet && !streaming {
+		return httpClient.hedge(httpRequest, route, attempts)
+	}
+	return httpClient.attempt(httpRequest, nextAttempt(attempts))
+}
+
+// Performs a single attempt of a http request in its own child span and reads the whole response body.
+// Returns http response and response body.
+func (httpClient *HttpClient) attempt(httpRequest *http.Request, attempt int) (*http.Response, []byte, error) {
+	ctx, span := httpClient.tracer.StartSpan(httpRequest.Context(), fmt.Sprintf("HTTP %s attempt %d", httpRequest.Method, attempt))
+	defer span.End()
+	span.SetAttribute("http.url", httpRequest.URL.String())
+	span.SetAttribute("attempt", attempt)
+
+	httpRequest = httpRequest.Clone(withClientTrace(ctx, span))
+	if httpRequest.GetBody != nil {
+		body, err := httpRequest.GetBody()
+		if err != nil {
+			span.RecordError(err)
+			return nil, nil, err
+		}
+		httpRequest.Body = body
+	}
+	injectSpanContext(httpRequest.Header, span.SpanContext())
+
+	var breaker *circuitBreaker
+	var generation uint64
+	if httpClient.breakers != nil {
+		breaker = httpClient.breakers.get(httpRequest.URL.Host)
+		var err error
+		if generation, err = breaker.allow(); err != nil {
+			err = fmt.Errorf("%w (host=%s, request_id=%s)", err, httpRequest.URL.Host, httpRequest.Header.Get(REQUEST_ID_HEADER))
+			span.RecordError(err)
+			return nil, nil, err
+		}
+	}
+
+	// The circuit is checked first, so requests to an open circuit fail without waiting for a slot.
+	if httpClient.limiter != nil {
+		if err := httpClient.limiter.Wait(ctx); err != nil {
+			if breaker != nil {
+				breaker.record(generation, circuitIgnored)
+			}
+			span.RecordError(err)
+			return nil, nil, fmt.Errorf("waiting for rate limiter: %w (request_id=%s)", err, httpRequest.Header.Get(REQUEST_ID_HEADER))
+		}
+	}
+	if httpClient.bulkhead != nil {
+		if err := httpClient.bulkhead.acquire(ctx); err != nil {
+			if breaker != nil {
+				breaker.record(generation, circuitIgnored)
+			}
+			span.RecordError(err)
+			return nil, nil, fmt.Errorf("waiting for concurrency slot: %w (request_id=%s)", err, httpRequest.Header.Get(REQUEST_ID_HEADER))
+		}
+		defer httpClient.bulkhead.release()
+	}
+
+	httpResponse, responseBytes, err := httpClient.roundTrip(httpRequest, attempt)
+	if breaker != nil {
+		breaker.record(generation, circuitOutcomeOf(httpResponse, err))
+	}
+	if httpClient.limiter != nil && httpResponse != nil {
+		if httpResponse.StatusCode == http.StatusTooManyRequests {
+			retryAfter := parseRetryAfter(httpResponse.Header)
+			httpClient.limiter.Backoff(retryAfter)
+			httpClient.logger.Log(LOG_LEVEL_WARN, "request throttled",
+				Field("retry_after", retryAfter), Field("rate", httpClient.limiter.Rate()))
+		} else if httpResponse.StatusCode < 400 {
+			httpClient.limiter.Recover()
+		}
+	}
+	if httpResponse != nil {
+		span.SetStatus(httpResponse.StatusCode)
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	return httpResponse, responseBytes, err
+}
+
+// Sends the http request and reads the whole response body, logging the outcome.
+// Returns http response and response body.
+func (httpClient *HttpClient) roundTrip(httpRequest *http.Request, attempt int) (*http.Response, []byte, error) {
+	requestID := httpRequest.Header.Get(REQUEST_ID_HEADER)
+	fields := []LogField{
+		Field("method", httpRequest.Method),
+		Field("url", httpRequest.URL.String()),
+		Field("attempt", attempt),
+		Field("request_id", requestID),
+	}
+	httpClient.logger.Log(LOG_LEVEL_DEBUG, "request started", append(fields, Field("headers", httpRequest.Header))...)
 
+	start := time.Now()
+	httpResponse, err := httpClient.client.Do(httpRequest)
+	if err != nil {
+		httpClient.logger.Log(LOG_LEVEL_WARN, "request failed",
+			append(fields, Field("duration", time.Since(start)), Field("error", err))...)
+		return nil, nil, fmt.Errorf("%w (request_id=%s)", err, requestID)
+	}
 	defer httpResponse.Body.Close()
 
-	responseBytes, err := ioutil.ReadAll(httpResponse.Body)
+	serverRequestID := httpResponse.Header.Get(REQUEST_ID_HEADER)
+	fields = append(fields, Field("status", httpResponse.StatusCode), Field("server_request_id", serverRequestID))
+	var responseBytes []byte
+	body, err := decompressedBody(httpResponse)
+	if err == nil {
+		defer body.Close()
+		limitedBody := limitResponseSize(body, httpClient.maxSize)
+		if consumer := bodyConsumerFromContext(httpRequest.Context()); consumer != nil && httpResponse.StatusCode < 300 {
+			err = consumer(limitedBody)
+		} else {
+			responseBytes, err = ioutil.ReadAll(limitedBody)
+		}
+	}
 	if err != nil {
-		return nil, err
+		httpClient.logger.Log(LOG_LEVEL_WARN, "request failed",
+			append(fields, Field("duration", time.Since(start)), Field("error", err))...)
+		return httpResponse, nil, fmt.Errorf("%w (request_id=%s, server_request_id=%s)", err, requestID, serverRequestID)
 	}
+	httpClient.logger.Log(LOG_LEVEL_INFO, "request finished", append(fields, Field("duration", time.Since(start)))...)
+
+	return httpResponse, responseBytes, nil
+}
 
+// Decodes the response body into response data and link data, or into a response error
+// when the API returned an error message. A 304 Not Modified response leaves both untouched.
+func (httpClient *HttpClient) decodeResponseBody(httpResponse *http.Response, responseBytes []byte, responseData interface{}, linkData interface{}) error {
+	if httpResponse.StatusCode == http.StatusNotModified {
+		return nil
+	}
 	responseError := &ResponseError{}
 	_ = json.Unmarshal(responseBytes, responseError)
 	if responseError.Message != "" {
-		return httpResponse, errors.New(responseError.Message)
+		responseError.Message = httpClient.redactor.String(responseError.Message)
+		responseError.StatusCode = httpResponse.StatusCode
+		responseError.RequestID = httpResponse.Request.Header.Get(REQUEST_ID_HEADER)
+		responseError.ServerRequestID = httpResponse.Header.Get(REQUEST_ID_HEADER)
+		return responseError
 	}
 
 	if responseData != nil && linkData != nil {
 		responseBody := &ResponseBody{}
-		err = json.Unmarshal(responseBytes, responseBody)
+		err := json.Unmarshal(responseBytes, responseBody)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		encodedData, err := json.Marshal(responseBody.Data)
 		if err == nil {
 			err = json.Unmarshal(encodedData, responseData)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 
@@ -143,10 +501,37 @@ func (httpClient *HttpClient) perform(ctx context.Context, httpRequest *http.Req
 		if err == nil {
 			err = json.Unmarshal(encodedLinks, linkData)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
+		return err
 	}
 
-	return httpResponse, err
+	return nil
+}
+
+// Returns the API error message along with the client and server request IDs.
+func (responseError *ResponseError) Error() string {
+	message := responseError.Message
+	if responseError.RequestID != "" {
+		message = fmt.Sprintf("%s (request_id=%s", message, responseError.RequestID)
+		if responseError.ServerRequestID != "" {
+			message = fmt.Sprintf("%s, server_request_id=%s", message, responseError.ServerRequestID)
+		}
+		message += ")"
+	}
+	return message
+}
+
+// Returns the circuit breaker state of the host, which is closed when no circuit breaker is configured.
+func (httpClient *HttpClient) CircuitState(host string) CircuitState {
+	if httpClient.breakers == nil {
+		return CIRCUIT_CLOSED
+	}
+	return httpClient.breakers.get(host).currentState()
+}
+
+func isResponseError(err error) bool {
+	var responseError *ResponseError
+	return errors.As(err, &responseError)
 }
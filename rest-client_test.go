@@ -32,7 +32,7 @@ func TestRestClient_Get(t *testing.T) {
 	expectedResponse := populateSingleAccountDataUnitTest()
 	accountResponse := new(AccountData)
 	links := new(Links)
-	url := fetchAccountApiUrl(httpClient.BaseURL, SINGLE_ACCOUNT_ID)
+	url, _ := fetchAccountApiUrl(httpClient.BaseURL, SINGLE_ACCOUNT_ID)
 
 	res, err := httpClient.Get(url, nil, accountResponse, links)
 	if err != nil {
@@ -125,7 +125,7 @@ func TestRestClient_Delete(t *testing.T) {
 		w.WriteHeader(http.StatusNoContent)
 	})
 
-	url := deleteAccountApiUrl(httpClient.BaseURL, SINGLE_ACCOUNT_ID, 0)
+	url, _ := deleteAccountApiUrl(httpClient.BaseURL, SINGLE_ACCOUNT_ID, 0)
 	res, err := httpClient.Delete(url)
 
 	if err != nil {
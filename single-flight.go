@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type fetchResult struct {
+	account       *AccountData
+	links         *Links
+	httpResponse  *http.Response
+	responseBytes []byte
+	err           error
+}
+
+type flightCall struct {
+	done    chan struct{}
+	result  fetchResult
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Coalesces concurrent fetches of the same account into one in-flight request.
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall
+}
+
+// Context carrying the values of its parent but none of its cancellation, so a shared request
+// outlives the caller which started it.
+type detachedContext struct {
+	parent context.Context
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: map[string]*flightCall{}}
+}
+
+// Runs fetch once for all concurrent callers of the same key. Each caller stops waiting when its
+// own context is done, and the shared fetch is only cancelled once every caller has stopped waiting.
+// Returns the shared result, which callers must not modify.
+func (group *flightGroup) do(ctx context.Context, key string, fetch func(ctx context.Context) fetchResult) fetchResult {
+	group.mutex.Lock()
+	call, ok := group.calls[key]
+	if !ok {
+		fetchCtx, cancel := context.WithCancel(detachedContext{parent: ctx})
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		group.calls[key] = call
+		go func() {
+			call.result = fetch(fetchCtx)
+			group.mutex.Lock()
+			if group.calls[key] == call {
+				delete(group.calls, key)
+			}
+			group.mutex.Unlock()
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	group.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result
+	case <-ctx.Done():
+		group.mutex.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			if group.calls[key] == call {
+				delete(group.calls, key)
+			}
+		}
+		group.mutex.Unlock()
+		return fetchResult{err: ctx.Err()}
+	}
+}
+
+// Creates the fetch result, reading the response body so every caller can be given its own copy.
+func newFetchResult(account *AccountData, links *Links, httpResponse *http.Response, err error) fetchResult {
+	result := fetchResult{account: account, links: links, httpResponse: httpResponse, err: err}
+	if httpResponse != nil && httpResponse.Body != nil {
+		result.responseBytes, _ = ioutil.ReadAll(httpResponse.Body)
+		httpResponse.Body.Close()
+	}
+	return result
+}
+
+// Returns a copy of the shared response with its own header and body, so callers can read and
+// modify it without affecting each other.
+func (result fetchResult) response() *http.Response {
+	if result.httpResponse == nil {
+		return nil
+	}
+	copied := *result.httpResponse
+	copied.Header = result.httpResponse.Header.Clone()
+	copied.Body = ioutil.NopCloser(bytes.NewReader(result.responseBytes))
+	return &copied
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Returns a handler which signals entered on its first request and answers once release is closed.
+func blockingAccountHandler(hits *int32, entered chan struct{}, release chan struct{}) http.HandlerFunc {
+	var once sync.Once
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		once.Do(func() { close(entered) })
+		<-release
+		w.Header().Set("X-Shared", "value")
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	}
+}
+
+// Waits until the number of callers waiting for the in-flight fetch of the key is reached.
+func waitForFlightWaiters(t *testing.T, group *flightGroup, key string, waiters int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		group.mutex.Lock()
+		call, ok := group.calls[key]
+		joined := ok && call.waiters == waiters
+		group.mutex.Unlock()
+		if joined {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("FAILED: expected %d callers to wait for %s", waiters, key)
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestSingleFlight_ConcurrentFetchesShareRequest(t *testing.T) {
+	var hits int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, blockingAccountHandler(&hits, entered, release))
+	defer closeServer()
+
+	const callers = 10
+	accounts := make([]*AccountData, callers)
+	var group sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		group.Add(1)
+		go func(i int) {
+			defer group.Done()
+			account, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+			if err != nil {
+				t.Errorf("FAILED: FetchById returned error: %v", err)
+			}
+			accounts[i] = account
+		}(i)
+	}
+	<-entered
+	waitForFlightWaiters(t, accountClient.flights, SINGLE_ACCOUNT_ID, callers)
+	close(release)
+	group.Wait()
+
+	if hits != 1 {
+		t.Errorf("FAILED: expected a single request for concurrent fetches, got %d", hits)
+	}
+	if accounts[0] == nil || accounts[1] == nil || accounts[0] == accounts[1] {
+		t.Fatalf("FAILED: expected every caller to get its own copy of the account")
+	}
+	accounts[0].Attributes.Name[0] = "changed by caller"
+	if accounts[1].Attributes.Name[0] == "changed by caller" {
+		t.Errorf("FAILED: expected account copies not to share attributes")
+	}
+	t.Log("SUCCESS: concurrent fetches shared a single request")
+}
+
+func TestSingleFlight_ConcurrentFetchesGetOwnResponse(t *testing.T) {
+	var hits int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, blockingAccountHandler(&hits, entered, release))
+	defer closeServer()
+
+	const callers = 5
+	bodies := make([][]byte, callers)
+	var group sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		group.Add(1)
+		go func(i int) {
+			defer group.Done()
+			_, _, httpResponse, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+			if err != nil || httpResponse == nil {
+				t.Errorf("FAILED: FetchById returned error: %v", err)
+				return
+			}
+			httpResponse.Header.Set("X-Shared", fmt.Sprint(i))
+			bodies[i], _ = ioutil.ReadAll(httpResponse.Body)
+			httpResponse.Body.Close()
+		}(i)
+	}
+	<-entered
+	waitForFlightWaiters(t, accountClient.flights, SINGLE_ACCOUNT_ID, callers)
+	close(release)
+	group.Wait()
+
+	for i, body := range bodies {
+		if len(body) != len(SINGLE_ACCOUNT_MOCK_RESPONSE) {
+			t.Errorf("FAILED: expected caller %d to read the whole body, got %d bytes", i, len(body))
+		}
+	}
+	_, _, httpResponse, _ := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if httpResponse.Header.Get("X-Shared") != "value" {
+		t.Errorf("FAILED: expected header changes of callers not to be shared")
+	}
+	t.Log("SUCCESS: every coalesced caller read its own response body")
+}
+
+func TestSingleFlight_CancelledCallerDoesNotCancelOthers(t *testing.T) {
+	var hits int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, blockingAccountHandler(&hits, entered, release))
+	defer closeServer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, _, _, err := accountClient.FetchByIdWithContext(ctx, SINGLE_ACCOUNT_ID)
+		cancelled <- err
+	}()
+	<-entered
+
+	result := make(chan error, 1)
+	go func() {
+		_, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+		result <- err
+	}()
+	waitForFlightWaiters(t, accountClient.flights, SINGLE_ACCOUNT_ID, 2)
+	cancel()
+
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Errorf("FAILED: expected the cancelled caller to get context.Canceled, got %v", err)
+	}
+	close(release)
+	if err := <-result; err != nil {
+		t.Errorf("FAILED: expected the other caller to get the account, got %v", err)
+	}
+	if hits != 1 {
+		t.Errorf("FAILED: expected the shared request to be sent once, got %d", hits)
+	}
+}
+
+func TestSingleFlight_LastCallerCancelsSharedRequest(t *testing.T) {
+	serverCancelled := make(chan struct{}, 1)
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			serverCancelled <- struct{}{}
+		case <-time.After(2 * time.Second):
+		}
+	})
+	defer closeServer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, _, _, err := accountClient.FetchByIdWithContext(ctx, SINGLE_ACCOUNT_ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FAILED: expected deadline exceeded, got %v", err)
+	}
+	select {
+	case <-serverCancelled:
+	case <-time.After(time.Second):
+		t.Errorf("FAILED: expected the shared request to be cancelled once nobody waits for it")
+	}
+}
+
+func TestSingleFlight_SequentialFetchesAreNotShared(t *testing.T) {
+	var hits int32
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	})
+	defer closeServer()
+
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if hits != 2 {
+		t.Errorf("FAILED: expected a request per sequential fetch, got %d", hits)
+	}
+}
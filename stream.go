@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Error returned when a response body is larger than the configured maximum response size.
+var ErrResponseTooLarge = errors.New("response body exceeds maximum size")
+
+// Consumes the body of a successful response as it arrives, instead of it being read into memory.
+type bodyConsumer func(body io.Reader) error
+
+type bodyConsumerKey struct{}
+
+// Outcome of an account list streamed to a channel.
+type AccountStreamResult struct {
+	Links        *Links
+	HttpResponse *http.Response
+	Err          error
+}
+
+// Reader failing with ErrResponseTooLarge once more than the remaining bytes are read.
+type sizeLimitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+// List accounts with optional page parameters, decoding the accounts one at a time as they arrive
+// and passing each to the handler. Decoding stops at the first error returned by the handler.
+// Returns links and http response.
+func (accountClient *AccountClient) ListAccountStream(params *AccountParams, handler func(account *AccountData) error) (*Links, *http.Response, error) {
+	return accountClient.ListAccountStreamWithContext(context.Background(), params, handler)
+}
+
+// List accounts using context with optional page parameters, decoding the accounts one at a time
+// as they arrive and passing each to the handler. Decoding stops at the first error returned by the handler.
+// Returns links and http response.
+func (accountClient *AccountClient) ListAccountStreamWithContext(ctx context.Context, params *AccountParams, handler func(account *AccountData) error) (*Links, *http.Response, error) {
+	links := new(Links)
+	var handlerErr error
+	consumer := func(body io.Reader) error {
+		return decodeAccountStream(body, links, func(account *AccountData) bool {
+			handlerErr = handler(account)
+			return handlerErr == nil
+		})
+	}
+	listURL, err := listAccountApiUrl(accountClient.HttpClient.BaseURL, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	httpRequest, err := accountClient.HttpClient.newHttpRequest("GET", listURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	httpResponse, err := accountClient.HttpClient.perform(contextWithBodyConsumer(ctx, consumer), httpRequest, nil, nil)
+	if err == nil {
+		err = handlerErr
+	}
+	if err != nil {
+		accountClient.HttpClient.logger.Log(LOG_LEVEL_ERROR, "Error occurred while streaming account list",
+			Field("error", err))
+		return nil, httpResponse, err
+	}
+	return links, httpResponse, nil
+}
+
+// List accounts using context with optional page parameters, sending the accounts on the returned
+// channel one at a time as they arrive. The account channel is closed once the list ends, after which
+// the outcome is sent on the result channel. Callers which stop receiving accounts early must cancel
+// the context, which stops the request and fails the result with the context error.
+// Returns the account channel and the result channel.
+func (accountClient *AccountClient) ListAccountChannel(ctx context.Context, params *AccountParams) (<-chan *AccountData, <-chan AccountStreamResult) {
+	accounts := make(chan *AccountData)
+	result := make(chan AccountStreamResult, 1)
+	go func() {
+		defer close(result)
+		links, httpResponse, err := accountClient.ListAccountStreamWithContext(ctx, params, func(account *AccountData) error {
+			select {
+			case accounts <- account:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(accounts)
+		result <- AccountStreamResult{Links: links, HttpResponse: httpResponse, Err: err}
+	}()
+	return accounts, result
+}
+
+// Decodes a response envelope, passing the elements of its data array to yield one at a time and
+// the links to links. Decoding stops without error when yield returns false.
+func decodeAccountStream(body io.Reader, links *Links, yield func(account *AccountData) bool) error {
+	decoder := json.NewDecoder(body)
+	if err := expectDelimiter(decoder, '{'); err != nil {
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case "data":
+			if err := expectDelimiter(decoder, '['); err != nil {
+				return err
+			}
+			for decoder.More() {
+				account := new(AccountData)
+				if err := decoder.Decode(account); err != nil {
+					return err
+				}
+				if !yield(account) {
+					return nil
+				}
+			}
+			if err := expectDelimiter(decoder, ']'); err != nil {
+				return err
+			}
+		case "links":
+			if err := decoder.Decode(links); err != nil {
+				return err
+			}
+		default:
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return err
+			}
+		}
+	}
+	return expectDelimiter(decoder, '}')
+}
+
+// Reads the next token, failing unless it is the delimiter.
+func expectDelimiter(decoder *json.Decoder, delimiter json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delimiter {
+		return fmt.Errorf("expected %v in response body, got %v", delimiter, token)
+	}
+	return nil
+}
+
+// Returns a copy of the context which streams successful response bodies to the consumer.
+func contextWithBodyConsumer(ctx context.Context, consumer bodyConsumer) context.Context {
+	return context.WithValue(ctx, bodyConsumerKey{}, consumer)
+}
+
+// Returns the body consumer of the context, or nil when the response body is read into memory.
+func bodyConsumerFromContext(ctx context.Context) bodyConsumer {
+	consumer, _ := ctx.Value(bodyConsumerKey{}).(bodyConsumer)
+	return consumer
+}
+
+// Limits the reader to limit bytes, leaving it unlimited when limit is not positive.
+func limitResponseSize(reader io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return reader
+	}
+	return &sizeLimitedReader{reader: reader, remaining: limit}
+}
+
+func (limited *sizeLimitedReader) Read(buffer []byte) (int, error) {
+	if limited.remaining <= 0 {
+		var probe [1]byte
+		n, err := limited.reader.Read(probe[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(buffer)) > limited.remaining {
+		buffer = buffer[:limited.remaining]
+	}
+	n, err := limited.reader.Read(buffer)
+	limited.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStream_ListAccountStream(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, MULTI_ACCOUNT_MOCK_RESPONSE)
+	})
+	defer closeServer()
+
+	var ids []string
+	links, res, err := accountClient.ListAccountStream(&AccountParams{Number: "0", Size: 2}, func(account *AccountData) error {
+		ids = append(ids, account.ID)
+		return nil
+	})
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("FAILED: ListAccountStream returned %v %v", res, err)
+	}
+	if len(ids) != 2 || ids[0] != "b91afcdb-62d2-4185-b23d-71c98eaab836" || ids[1] != "515c7029-ca03-5d4d-8d33-ddf288c6cbac" {
+		t.Errorf("FAILED: expected both accounts in order, got %v", ids)
+	}
+	if links.Next != "/v1/organisation/accounts?page%5Bnumber%5D=1&page%5Bsize%5D=2" {
+		t.Errorf("FAILED: expected links to be decoded, got %+v", links)
+	}
+	t.Log("SUCCESS: accounts were streamed one at a time")
+}
+
+func TestStream_ListAccountChannel(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, MULTI_ACCOUNT_MOCK_RESPONSE)
+	})
+	defer closeServer()
+
+	accounts, results := accountClient.ListAccountChannel(context.Background(), &AccountParams{Number: "0", Size: 2})
+	var ids []string
+	for account := range accounts {
+		ids = append(ids, account.ID)
+	}
+	result := <-results
+	if result.Err != nil || result.HttpResponse.StatusCode != http.StatusOK || result.Links.Next == "" {
+		t.Fatalf("FAILED: ListAccountChannel returned %+v", result)
+	}
+	if len(ids) != 2 || ids[0] != "b91afcdb-62d2-4185-b23d-71c98eaab836" {
+		t.Errorf("FAILED: expected both accounts in order, got %v", ids)
+	} else {
+		t.Log("SUCCESS: accounts were sent on the channel one at a time")
+	}
+}
+
+func TestStream_ListAccountChannelCancelled(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, MULTI_ACCOUNT_MOCK_RESPONSE)
+	})
+	defer closeServer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	accounts, results := accountClient.ListAccountChannel(ctx, nil)
+	<-accounts
+	cancel()
+	for range accounts {
+	}
+	if result := <-results; !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("FAILED: expected the cancelled stream to fail with context.Canceled, got %v", result.Err)
+	}
+}
+
+func TestStream_LinksBeforeData(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"links": {"self": "/self"}, "meta": {"count": 1}, "data": [{"id": "a"}]}`)
+	})
+	defer closeServer()
+
+	count := 0
+	links, _, err := accountClient.ListAccountStream(nil, func(account *AccountData) error {
+		count++
+		return nil
+	})
+	if err != nil || count != 1 || links.Self != "/self" {
+		t.Errorf("FAILED: expected one account and links, got %d %+v %v", count, links, err)
+	}
+}
+
+func TestStream_HandlerErrorStopsDecoding(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, MULTI_ACCOUNT_MOCK_RESPONSE)
+	})
+	defer closeServer()
+
+	stop := errors.New("stop")
+	count := 0
+	_, _, err := accountClient.ListAccountStream(nil, func(account *AccountData) error {
+		count++
+		return stop
+	})
+	if !errors.Is(err, stop) || count != 1 {
+		t.Errorf("FAILED: expected the handler error after one account, got %d %v", count, err)
+	}
+}
+
+func TestStream_MaxResponseSize(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{MaxResponseSize: 512}, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, MULTI_ACCOUNT_MOCK_RESPONSE)
+	})
+	defer closeServer()
+
+	_, _, err := accountClient.ListAccountStream(nil, func(account *AccountData) error {
+		return nil
+	})
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("FAILED: expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func TestStream_ErrorResponse(t *testing.T) {
+	accountClient, closeServer := prepareTestAccountClientWithSetting(nil, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, WRONG_ACCOUNT_MOCK_RESPONSE)
+	})
+	defer closeServer()
+
+	_, res, err := accountClient.ListAccountStream(nil, func(account *AccountData) error {
+		t.Errorf("FAILED: expected no account for an error response")
+		return nil
+	})
+	if !isResponseError(err) || res.StatusCode != http.StatusBadRequest {
+		t.Errorf("FAILED: expected a response error, got %v %v", res, err)
+	}
+}
+
+func TestStream_MalformedBody(t *testing.T) {
+	links := new(Links)
+	err := decodeAccountStream(strings.NewReader(`{"data": {"id": "a"}}`), links, func(account *AccountData) bool {
+		return true
+	})
+	if err == nil {
+		t.Errorf("FAILED: expected an error when data is not an array")
+	}
+}
+
+func TestStream_SizeLimitedReader(t *testing.T) {
+	exact, err := io.ReadAll(limitResponseSize(strings.NewReader("12345"), 5))
+	if err != nil || string(exact) != "12345" {
+		t.Errorf("FAILED: expected a body of exactly the limit to be read, got %q %v", exact, err)
+	}
+	if _, err := io.ReadAll(limitResponseSize(strings.NewReader("123456"), 5)); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("FAILED: expected ErrResponseTooLarge, got %v", err)
+	}
+}
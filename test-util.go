@@ -108,6 +108,9 @@ const (
 	}`
 )
 
+// Organisation IDs of integration test accounts start with this prefix, so leftovers can be swept.
+const INTEGRATION_ORGANISATION_ID_PREFIX = "7e570000-"
+
 func uuid() string {
 	b := make([]byte, 16)
 	rand.Read(b)
@@ -177,7 +180,7 @@ func populateWrongAccountDataUnitTest() *AccountData {
 func populateAccountDataIntegration() *AccountData {
 	attributes := populateAccountAttributes()
 	id := uuid()
-	orgId := uuid()
+	orgId := INTEGRATION_ORGANISATION_ID_PREFIX + uuid()[len(INTEGRATION_ORGANISATION_ID_PREFIX):]
 	var version int64 = 0
 	currentTime := time.Now()
 	accountData := &AccountData{
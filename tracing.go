@@ -0,0 +1,291 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TRACEPARENT_HEADER = "traceparent"
+	TRACESTATE_HEADER  = "tracestate"
+)
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent header")
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+// Identifies a span following the W3C trace context specification.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+// Starts spans around client requests. Implementations adapt the client to a tracing system.
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Records the outcome and timings of a single operation.
+type Span interface {
+	SpanContext() SpanContext
+	SetAttribute(key string, value interface{})
+	AddEvent(name string, timestamp time.Time)
+	RecordError(err error)
+	SetStatus(status int)
+	End()
+}
+
+type spanContextKey struct{}
+
+type noopTracer struct{}
+
+type noopSpan struct {
+	spanContext SpanContext
+}
+
+// Keeps finished spans in memory, intended for tests.
+type InMemoryTracer struct {
+	mutex sync.Mutex
+	spans []*RecordedSpan
+}
+
+// Span recorded by the in-memory tracer.
+type RecordedSpan struct {
+	mutex      sync.Mutex
+	tracer     *InMemoryTracer
+	Name       string
+	Context    SpanContext
+	Parent     SpanID
+	Attributes map[string]interface{}
+	Events     []SpanEvent
+	Err        error
+	Status     int
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+type SpanEvent struct {
+	Name      string
+	Timestamp time.Time
+}
+
+// Returns whether the trace and span IDs are set.
+func (spanContext SpanContext) IsValid() bool {
+	return spanContext.TraceID != TraceID{} && spanContext.SpanID != SpanID{}
+}
+
+// Formats the span context as a version 00 traceparent header value.
+func (spanContext SpanContext) Traceparent() string {
+	flags := "00"
+	if spanContext.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(spanContext.TraceID[:]), hex.EncodeToString(spanContext.SpanID[:]), flags)
+}
+
+// Parses traceparent and tracestate header values into a span context.
+func ParseTraceparent(traceparent, tracestate string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	spanContext := SpanContext{TraceState: tracestate}
+	version := make([]byte, 1)
+	flags := make([]byte, 1)
+	if _, err := hex.Decode(version, []byte(parts[0])); err != nil {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(spanContext.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(spanContext.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(flags, []byte(parts[3])); err != nil {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if !spanContext.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	spanContext.Sampled = flags[0]&0x01 == 0x01
+	return spanContext, nil
+}
+
+// Extracts the span context from inbound traceparent and tracestate headers.
+func ExtractSpanContext(header http.Header) (SpanContext, bool) {
+	spanContext, err := ParseTraceparent(header.Get(TRACEPARENT_HEADER), header.Get(TRACESTATE_HEADER))
+	return spanContext, err == nil
+}
+
+// Returns a copy of the context carrying the span context as parent of the client's spans.
+func ContextWithSpanContext(ctx context.Context, spanContext SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, spanContext)
+}
+
+// Returns the span context carried by the context.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	spanContext, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return spanContext, ok
+}
+
+// Creates a tracer which records nothing but still propagates a parent span context found in the context.
+func NewNoopTracer() Tracer {
+	return noopTracer{}
+}
+
+func (noopTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	spanContext, _ := SpanContextFromContext(ctx)
+	return ctx, &noopSpan{spanContext: spanContext}
+}
+
+func (noopSpan *noopSpan) SpanContext() SpanContext { return noopSpan.spanContext }
+
+func (noopSpan *noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan *noopSpan) AddEvent(name string, timestamp time.Time) {}
+
+func (noopSpan *noopSpan) RecordError(err error) {}
+
+func (noopSpan *noopSpan) SetStatus(status int) {}
+
+func (noopSpan *noopSpan) End() {}
+
+// Creates a tracer keeping finished spans in memory.
+func NewInMemoryTracer() *InMemoryTracer {
+	return &InMemoryTracer{}
+}
+
+func (tracer *InMemoryTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := SpanContextFromContext(ctx)
+	span := &RecordedSpan{
+		tracer:     tracer,
+		Name:       name,
+		Parent:     parent.SpanID,
+		Attributes: map[string]interface{}{},
+		StartTime:  time.Now(),
+		Context: SpanContext{
+			TraceID:    parent.TraceID,
+			Sampled:    true,
+			TraceState: parent.TraceState,
+		},
+	}
+	if !parent.IsValid() {
+		_, _ = rand.Read(span.Context.TraceID[:])
+	}
+	_, _ = rand.Read(span.Context.SpanID[:])
+	return ContextWithSpanContext(ctx, span.Context), span
+}
+
+// Returns the finished spans in the order they ended.
+func (tracer *InMemoryTracer) Spans() []*RecordedSpan {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	return append([]*RecordedSpan{}, tracer.spans...)
+}
+
+// Removes every recorded span.
+func (tracer *InMemoryTracer) Reset() {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	tracer.spans = nil
+}
+
+func (span *RecordedSpan) SpanContext() SpanContext {
+	return span.Context
+}
+
+func (span *RecordedSpan) SetAttribute(key string, value interface{}) {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	span.Attributes[key] = value
+}
+
+func (span *RecordedSpan) AddEvent(name string, timestamp time.Time) {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	span.Events = append(span.Events, SpanEvent{Name: name, Timestamp: timestamp})
+}
+
+func (span *RecordedSpan) RecordError(err error) {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	span.Err = err
+}
+
+func (span *RecordedSpan) SetStatus(status int) {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	span.Status = status
+}
+
+func (span *RecordedSpan) End() {
+	span.mutex.Lock()
+	span.EndTime = time.Now()
+	span.mutex.Unlock()
+
+	span.tracer.mutex.Lock()
+	defer span.tracer.mutex.Unlock()
+	span.tracer.spans = append(span.tracer.spans, span)
+}
+
+// Injects the span context into outgoing traceparent and tracestate headers.
+func injectSpanContext(header http.Header, spanContext SpanContext) {
+	if !spanContext.IsValid() {
+		return
+	}
+	header.Set(TRACEPARENT_HEADER, spanContext.Traceparent())
+	if spanContext.TraceState != "" {
+		header.Set(TRACESTATE_HEADER, spanContext.TraceState)
+	}
+}
+
+// Returns a copy of the context recording DNS, connect, TLS and first byte timings on the span.
+func withClientTrace(ctx context.Context, span Span) context.Context {
+	var mutex sync.Mutex
+	started := map[string]time.Time{}
+	begin := func(phase string) {
+		now := time.Now()
+		mutex.Lock()
+		started[phase] = now
+		mutex.Unlock()
+		span.AddEvent(phase+"_start", now)
+	}
+	done := func(phase string) {
+		now := time.Now()
+		mutex.Lock()
+		phaseStart := started[phase]
+		mutex.Unlock()
+		span.AddEvent(phase+"_done", now)
+		span.SetAttribute("http."+phase+"_duration", now.Sub(phaseStart))
+	}
+
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { begin("dns") },
+		DNSDone:           func(httptrace.DNSDoneInfo) { done("dns") },
+		ConnectStart:      func(network, addr string) { begin("connect") },
+		ConnectDone:       func(network, addr string, err error) { done("connect") },
+		TLSHandshakeStart: func() { begin("tls") },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { done("tls") },
+		GotFirstResponseByte: func() {
+			now := time.Now()
+			span.AddEvent("first_byte", now)
+			span.SetAttribute("http.time_to_first_byte", now.Sub(start))
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace)
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func findSpan(spans []*RecordedSpan, name string) *RecordedSpan {
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestTracing_SpansAndPropagation(t *testing.T) {
+	tracer := NewInMemoryTracer()
+	var traceparent, tracestate string
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{Tracer: tracer},
+		func(w http.ResponseWriter, r *http.Request) {
+			traceparent = r.Header.Get(TRACEPARENT_HEADER)
+			tracestate = r.Header.Get(TRACESTATE_HEADER)
+			fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+		})
+	defer closeServer()
+
+	parent, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "vendor=value")
+	if err != nil {
+		t.Fatalf("FAILED: ParseTraceparent returned error: %v", err)
+	}
+	ctx := ContextWithSpanContext(context.Background(), parent)
+	if _, _, _, err := accountClient.FetchByIdWithContext(ctx, SINGLE_ACCOUNT_ID); err != nil {
+		t.Fatalf("FAILED: FetchById returned error: %v", err)
+	}
+
+	spans := tracer.Spans()
+	requestSpan := findSpan(spans, "HTTP GET")
+	attemptSpan := findSpan(spans, "HTTP GET attempt 1")
+	if requestSpan == nil || attemptSpan == nil {
+		t.Fatalf("FAILED: expected request and attempt spans, got %+v", spans)
+	}
+	if requestSpan.Context.TraceID != parent.TraceID || requestSpan.Parent != parent.SpanID {
+		t.Errorf("FAILED: expected request span to continue the parent trace, got %+v", requestSpan.Context)
+	}
+	if attemptSpan.Parent != requestSpan.Context.SpanID {
+		t.Errorf("FAILED: expected attempt span to be a child of the request span")
+	}
+	if traceparent != attemptSpan.Context.Traceparent() || tracestate != "vendor=value" {
+		t.Errorf("FAILED: expected traceparent %v and tracestate, got %v %v", attemptSpan.Context.Traceparent(), traceparent, tracestate)
+	}
+	if requestSpan.Status != http.StatusOK || requestSpan.Attributes["http.route"] != "/v1/organisation/accounts/{id}" {
+		t.Errorf("FAILED: unexpected request span status %v attributes %+v", requestSpan.Status, requestSpan.Attributes)
+	}
+	if _, ok := attemptSpan.Attributes["http.time_to_first_byte"]; !ok {
+		t.Errorf("FAILED: expected first byte timing on attempt span, got %+v", attemptSpan.Attributes)
+	}
+	if _, ok := attemptSpan.Attributes["http.connect_duration"]; !ok {
+		t.Errorf("FAILED: expected connect timing on attempt span, got %+v", attemptSpan.Attributes)
+	}
+}
+
+func TestTracing_RecordsError(t *testing.T) {
+	tracer := NewInMemoryTracer()
+	accountClient, closeServer := prepareTestAccountClientWithSetting(&ClientSetting{Tracer: tracer},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, WRONG_ACCOUNT_MOCK_RESPONSE)
+		})
+	defer closeServer()
+
+	accountClient.FetchById(WRONG_ACCOUNT_ID)
+	requestSpan := findSpan(tracer.Spans(), "HTTP GET")
+	if requestSpan == nil || requestSpan.Err == nil || requestSpan.Status != http.StatusNotFound {
+		t.Errorf("FAILED: expected request span with error and 404 status, got %+v", requestSpan)
+	}
+}
+
+func TestTracing_NoopTracerPropagatesParent(t *testing.T) {
+	accountClient, multiplexer, closeServer := prepareTestAccountClient()
+	defer closeServer()
+
+	var traceparent string
+	multiplexer.HandleFunc(UNIT_ACCOUNTS_API_BASE+"/"+SINGLE_ACCOUNT_ID, func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(TRACEPARENT_HEADER)
+		fmt.Fprint(w, SINGLE_ACCOUNT_MOCK_RESPONSE)
+	})
+
+	inbound := http.Header{}
+	inbound.Set(TRACEPARENT_HEADER, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	parent, ok := ExtractSpanContext(inbound)
+	if !ok {
+		t.Fatalf("FAILED: expected inbound traceparent to be extracted")
+	}
+	accountClient.FetchByIdWithContext(ContextWithSpanContext(context.Background(), parent), SINGLE_ACCOUNT_ID)
+	if traceparent != inbound.Get(TRACEPARENT_HEADER) {
+		t.Errorf("FAILED: expected traceparent %v, got %v", inbound.Get(TRACEPARENT_HEADER), traceparent)
+	}
+}
+
+func TestTracing_ParseTraceparent(t *testing.T) {
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	}
+	for _, traceparent := range invalid {
+		if _, err := ParseTraceparent(traceparent, ""); err == nil {
+			t.Errorf("FAILED: expected %q to be rejected", traceparent)
+		}
+	}
+
+	spanContext, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "")
+	if err != nil || spanContext.Sampled {
+		t.Errorf("FAILED: expected unsampled span context, got %+v %v", spanContext, err)
+	}
+	if spanContext.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00" {
+		t.Errorf("FAILED: traceparent round trip returned %v", spanContext.Traceparent())
+	}
+}
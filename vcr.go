@@ -0,0 +1,333 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+type VCRMode int
+
+const (
+	// Answers requests from the cassette without sending them. It is the zero value, so a recorder
+	// never rewrites a cassette unless recording is asked for.
+	VCR_MODE_REPLAY VCRMode = iota
+	// Sends requests and appends every interaction to the cassette, replacing its previous content.
+	VCR_MODE_RECORD
+)
+
+// Parts of a request compared when looking up a recorded interaction.
+type VCRMatch int
+
+const (
+	VCR_MATCH_METHOD VCRMatch = 1 << iota
+	VCR_MATCH_PATH
+	VCR_MATCH_QUERY
+	VCR_MATCH_BODY
+	VCR_MATCH_DEFAULT = VCR_MATCH_METHOD | VCR_MATCH_PATH | VCR_MATCH_QUERY
+)
+
+// Error returned in replay mode for a request without a matching recorded interaction.
+var ErrNoRecordedInteraction = errors.New("no recorded interaction matches request")
+
+// Configures a VCR recorder.
+type VCRSetting struct {
+	// VCR_MODE_REPLAY when unset.
+	Mode VCRMode
+	// Parts of a request compared in replay mode, VCR_MATCH_DEFAULT when zero.
+	Match VCRMatch
+	// Round tripper sending requests in record mode, http.DefaultTransport when nil.
+	Transport http.RoundTripper
+	// Headers masked in the cassette in addition to REDACTED_HEADERS_DEFAULT.
+	RedactHeaders []string
+}
+
+// Request and response pair stored as one line of a cassette.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Round tripper recording interactions to a JSONL cassette, or replaying them from it.
+// Secrets and personal data are redacted before they are written.
+type Recorder struct {
+	mutex        sync.Mutex
+	setting      VCRSetting
+	redactor     *Redactor
+	cassette     *os.File
+	interactions []Interaction
+	used         []bool
+}
+
+// Creates a recorder for the cassette file, replaying it without a setting. In replay mode the
+// cassette is read up front.
+// Callers must close the recorder.
+func NewRecorder(cassette string, setting *VCRSetting) (*Recorder, error) {
+	recorder := &Recorder{}
+	if setting != nil {
+		recorder.setting = *setting
+	}
+	if recorder.setting.Match == 0 {
+		recorder.setting.Match = VCR_MATCH_DEFAULT
+	}
+	if recorder.setting.Transport == nil {
+		recorder.setting.Transport = http.DefaultTransport
+	}
+	recorder.redactor = NewRedactor(recorder.setting.RedactHeaders...)
+
+	if recorder.setting.Mode == VCR_MODE_RECORD {
+		file, err := os.Create(cassette)
+		if err != nil {
+			return nil, err
+		}
+		recorder.cassette = file
+		return recorder, nil
+	}
+
+	interactions, err := ReadCassette(cassette)
+	if err != nil {
+		return nil, err
+	}
+	recorder.interactions = interactions
+	recorder.used = make([]bool, len(interactions))
+	return recorder, nil
+}
+
+// Reads the interactions of a cassette file.
+func ReadCassette(cassette string) ([]Interaction, error) {
+	file, err := os.Open(cassette)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		interaction := Interaction{}
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("reading cassette %s line %d: %w", cassette, line, err)
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions, scanner.Err()
+}
+
+// Records or replays the request depending on the mode.
+func (recorder *Recorder) RoundTrip(httpRequest *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	recorded := RecordedRequest{
+		Method: httpRequest.Method,
+		URL:    httpRequest.URL.String(),
+		Header: recorder.redactor.Header(httpRequest.Header),
+		Body:   string(recorder.redactBody(requestBody)),
+	}
+	if recorder.setting.Mode == VCR_MODE_REPLAY {
+		return recorder.replay(httpRequest, recorded)
+	}
+	return recorder.record(httpRequest, recorded)
+}
+
+// Returns the recorded interactions which have not been replayed.
+func (recorder *Recorder) Unused() []Interaction {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	var unused []Interaction
+	for i, used := range recorder.used {
+		if !used {
+			unused = append(unused, recorder.interactions[i])
+		}
+	}
+	return unused
+}
+
+// Closes the cassette file.
+func (recorder *Recorder) Close() error {
+	if recorder.cassette == nil {
+		return nil
+	}
+	return recorder.cassette.Close()
+}
+
+// Sends the request and appends the interaction to the cassette.
+func (recorder *Recorder) record(httpRequest *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	httpResponse, err := recorder.setting.Transport.RoundTrip(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	body, err := decompressedBody(httpResponse)
+	if err != nil {
+		httpResponse.Body.Close()
+		return nil, err
+	}
+	responseBytes, err := ioutil.ReadAll(body)
+	body.Close()
+	httpResponse.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	httpResponse.Body = ioutil.NopCloser(bytes.NewReader(responseBytes))
+
+	interaction := Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: httpResponse.StatusCode,
+			Header:     recorder.redactor.Header(httpResponse.Header),
+			Body:       string(recorder.redactBody(responseBytes)),
+		},
+	}
+	line, err := json.Marshal(interaction)
+	if err != nil {
+		return nil, err
+	}
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if _, err := recorder.cassette.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	return httpResponse, nil
+}
+
+// Answers the request with the first unused recorded interaction matching it.
+func (recorder *Recorder) replay(httpRequest *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	for i, interaction := range recorder.interactions {
+		if recorder.used[i] || !recorder.matches(interaction.Request, recorded) {
+			continue
+		}
+		recorder.used[i] = true
+		response := interaction.Response
+		return &http.Response{
+			Status:        strconv.Itoa(response.StatusCode) + " " + http.StatusText(response.StatusCode),
+			StatusCode:    response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        response.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader([]byte(response.Body))),
+			ContentLength: int64(len(response.Body)),
+			Request:       httpRequest,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoRecordedInteraction, httpRequest.Method, httpRequest.URL.RequestURI())
+}
+
+// Returns whether the recorded request matches the request in the parts selected for matching.
+func (recorder *Recorder) matches(recorded RecordedRequest, request RecordedRequest) bool {
+	match := recorder.setting.Match
+	if match&VCR_MATCH_METHOD != 0 && recorded.Method != request.Method {
+		return false
+	}
+	recordedURL, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	requestURL, err := url.Parse(request.URL)
+	if err != nil {
+		return false
+	}
+	if match&VCR_MATCH_PATH != 0 && recordedURL.Path != requestURL.Path {
+		return false
+	}
+	if match&VCR_MATCH_QUERY != 0 && !reflect.DeepEqual(recordedURL.Query(), requestURL.Query()) {
+		return false
+	}
+	if match&VCR_MATCH_BODY != 0 && !equalBodies(recorded.Body, request.Body) {
+		return false
+	}
+	return true
+}
+
+// Returns the body with personal data masked. JSON keeps its shape so replayed bodies still decode.
+func (recorder *Recorder) redactBody(body []byte) []byte {
+	var document interface{}
+	if len(body) == 0 || json.Unmarshal(body, &document) != nil {
+		return []byte(recorder.redactor.String(string(body)))
+	}
+	redacted, err := json.Marshal(recorder.maskValue(document, false))
+	if err != nil {
+		return []byte(REDACTED_VALUE)
+	}
+	return redacted
+}
+
+// Masks the strings of personal data fields at any depth, keeping arrays and objects intact.
+func (recorder *Recorder) maskValue(value interface{}, sensitive bool) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			typed[key] = recorder.maskValue(nested, sensitive || recorder.redactor.keys[key])
+		}
+	case []interface{}:
+		for i, nested := range typed {
+			typed[i] = recorder.maskValue(nested, sensitive)
+		}
+	case string:
+		if sensitive {
+			return REDACTED_VALUE
+		}
+	}
+	return value
+}
+
+// Reads the request body from a copy when the request provides one, and otherwise leaves a fresh
+// copy on the request.
+func readRequestBody(httpRequest *http.Request) ([]byte, error) {
+	if httpRequest.Body == nil || httpRequest.Body == http.NoBody {
+		return nil, nil
+	}
+	if httpRequest.GetBody != nil {
+		body, err := httpRequest.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return ioutil.ReadAll(body)
+	}
+	body, err := ioutil.ReadAll(httpRequest.Body)
+	httpRequest.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// Compares two bodies as JSON documents when both parse, and as text otherwise.
+func equalBodies(first string, second string) bool {
+	var firstDocument, secondDocument interface{}
+	if json.Unmarshal([]byte(first), &firstDocument) == nil && json.Unmarshal([]byte(second), &secondDocument) == nil {
+		return reflect.DeepEqual(firstDocument, secondDocument)
+	}
+	return first == second
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"form3/rest-client/accountapitest"
+)
+
+func prepareTestVCRAccountClient(t *testing.T, baseURL string, cassette string, setting *VCRSetting) (*AccountClient, *Recorder) {
+	recorder, err := NewRecorder(cassette, setting)
+	if err != nil {
+		t.Fatalf("FAILED: NewRecorder returned error: %v", err)
+	}
+	t.Cleanup(func() { recorder.Close() })
+	httpClient := NewHttpClient(&ClientSetting{BaseURL: baseURL, Timeout: INTEGRATION_TIME_OUT, Transport: recorder})
+	return NewAccountClient(httpClient), recorder
+}
+
+func recordTestCassette(t *testing.T) (string, *AccountData) {
+	cassette := filepath.Join(t.TempDir(), "accounts.jsonl")
+	server := accountapitest.NewServer()
+	defer server.Close()
+	accountClient, recorder := prepareTestVCRAccountClient(t, server.BaseURL, cassette, &VCRSetting{Mode: VCR_MODE_RECORD})
+
+	payload := populateAccountDataIntegration()
+	if _, _, _, err := accountClient.CreateAccount(payload); err != nil {
+		t.Fatalf("FAILED: CreateAccount returned error: %v", err)
+	}
+	if _, _, _, err := accountClient.FetchById(payload.ID); err != nil {
+		t.Fatalf("FAILED: FetchById returned error: %v", err)
+	}
+	if _, err := accountClient.DeleteAccount(payload.ID, 0); err != nil {
+		t.Fatalf("FAILED: DeleteAccount returned error: %v", err)
+	}
+	recorder.Close()
+	return cassette, payload
+}
+
+func TestVCR_RecordAndReplay(t *testing.T) {
+	cassette, payload := recordTestCassette(t)
+	interactions, err := ReadCassette(cassette)
+	if err != nil || len(interactions) != 3 {
+		t.Fatalf("FAILED: expected 3 recorded interactions, got %d %v", len(interactions), err)
+	}
+
+	accountClient, recorder := prepareTestVCRAccountClient(t, "http://replay.invalid"+UNIT_ACCOUNTS_API_BASE, cassette,
+		&VCRSetting{Mode: VCR_MODE_REPLAY})
+	created, _, res, err := accountClient.CreateAccount(payload)
+	if err != nil || res.StatusCode != http.StatusCreated || created.ID != payload.ID {
+		t.Fatalf("FAILED: expected the recorded create response, got %v %v", res, err)
+	}
+	fetched, _, _, err := accountClient.FetchById(payload.ID)
+	if err != nil || fetched.ID != payload.ID || len(fetched.Attributes.Name) != 1 {
+		t.Fatalf("FAILED: expected the recorded account, got %+v %v", fetched, err)
+	}
+	if res, err := accountClient.DeleteAccount(payload.ID, 0); err != nil || res.StatusCode != http.StatusNoContent {
+		t.Errorf("FAILED: expected the recorded delete response, got %v %v", res, err)
+	}
+	if unused := recorder.Unused(); len(unused) != 0 {
+		t.Errorf("FAILED: expected every interaction to be replayed, got %v", unused)
+	}
+	t.Log("SUCCESS: interactions were recorded and replayed")
+}
+
+func TestVCR_CassetteIsRedacted(t *testing.T) {
+	cassette, payload := recordTestCassette(t)
+	content, _ := ioutil.ReadFile(cassette)
+	for _, secret := range []string{payload.Attributes.Name[0], payload.Attributes.Iban, payload.Attributes.AccountNumber} {
+		if strings.Contains(string(content), secret) {
+			t.Errorf("FAILED: expected %q to be redacted from the cassette", secret)
+		}
+	}
+	if !strings.Contains(string(content), payload.ID) {
+		t.Errorf("FAILED: expected account IDs to be kept in the cassette")
+	}
+}
+
+func TestVCR_UnmatchedRequestFails(t *testing.T) {
+	cassette, _ := recordTestCassette(t)
+	accountClient, _ := prepareTestVCRAccountClient(t, "http://replay.invalid"+UNIT_ACCOUNTS_API_BASE, cassette,
+		&VCRSetting{Mode: VCR_MODE_REPLAY})
+
+	_, _, _, err := accountClient.FetchById(SINGLE_ACCOUNT_ID)
+	if !errors.Is(err, ErrNoRecordedInteraction) || !strings.Contains(err.Error(), SINGLE_ACCOUNT_ID) {
+		t.Errorf("FAILED: expected an unmatched request error naming the request, got %v", err)
+	}
+}
+
+func TestVCR_MatchBody(t *testing.T) {
+	cassette, payload := recordTestCassette(t)
+	accountClient, _ := prepareTestVCRAccountClient(t, "http://replay.invalid"+UNIT_ACCOUNTS_API_BASE, cassette,
+		&VCRSetting{Mode: VCR_MODE_REPLAY, Match: VCR_MATCH_DEFAULT | VCR_MATCH_BODY})
+
+	changed := populateAccountDataIntegration()
+	changed.ID = payload.ID
+	changed.OrganisationID = payload.OrganisationID
+	changed.Type = "other"
+	if _, _, _, err := accountClient.CreateAccount(changed); !errors.Is(err, ErrNoRecordedInteraction) {
+		t.Errorf("FAILED: expected a different body not to match, got %v", err)
+	}
+	if _, _, _, err := accountClient.CreateAccount(payload); err != nil {
+		t.Errorf("FAILED: expected the recorded body to match, got %v", err)
+	}
+}
+
+func TestVCR_DefaultSettingReplays(t *testing.T) {
+	cassette, _ := recordTestCassette(t)
+	recorded, _ := ioutil.ReadFile(cassette)
+	for _, setting := range []*VCRSetting{nil, {}} {
+		recorder, err := NewRecorder(cassette, setting)
+		if err != nil {
+			t.Fatalf("FAILED: NewRecorder returned error: %v", err)
+		}
+		recorder.Close()
+		if content, _ := ioutil.ReadFile(cassette); string(content) != string(recorded) {
+			t.Fatalf("FAILED: expected setting %+v to leave the cassette untouched", setting)
+		}
+	}
+	t.Log("SUCCESS: recorders without a mode replay the cassette")
+}